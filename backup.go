@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// showBackupDialog 选择备份目录和选项后备份连接的环境
+func showBackupDialog(w fyne.Window, connectionIndex int) {
+	connection := config.Config.Connections[connectionIndex]
+
+	dirEntry := widget.NewEntry()
+	dirEntry.SetPlaceHolder("Directory to write the backup into")
+	browseButton := widget.NewButtonWithIcon("Browse", theme.FolderOpenIcon(), func() {
+		fd := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+			if dir != nil {
+				dirEntry.SetText(dir.Path())
+			}
+		}, w)
+		fd.Resize(fyne.NewSize(windowWidth, windowHeight))
+		fd.Show()
+	})
+	compactCheck := widget.NewCheck("Compact (omit free pages)", nil)
+	compactCheck.SetChecked(true)
+	registerCheck := widget.NewCheck("Add backup as a read-only connection", nil)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Connection", widget.NewLabel(connection.Name)),
+		widget.NewFormItem("Directory", container.NewBorder(nil, nil, nil, browseButton, dirEntry)),
+		widget.NewFormItem("", compactCheck),
+		widget.NewFormItem("", registerCheck),
+	}
+	form := dialog.NewForm("Backup", "Backup", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if dirEntry.Text == "" {
+			showErrorLog("Backup directory cannot be empty")
+			return
+		}
+		// 每次备份写入新的子目录，LMDB 不会覆盖已存在的 data.mdb
+		dir := filepath.Join(dirEntry.Text, backupDirName(connection.Name, time.Now()))
+		backupConnection(w, connectionIndex, dir, compactCheck.Checked, registerCheck.Checked)
+	}, w)
+	form.Resize(fyne.NewSize(windowWidth/2, 0))
+	form.Show()
+}
+
+// backupDirName 生成备份子目录名，例如 "orders-backup-20240102-150405"
+func backupDirName(name string, now time.Time) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	return name + "-backup-" + now.Format("20060102-150405")
+}
+
+// backupConnection 在后台复制环境，通过目标文件大小显示进度。
+// 当前打开的连接直接使用已打开的环境，同一进程中不能重复打开同一个环境
+func backupConnection(w fyne.Window, connectionIndex int, dir string, compact, register bool) {
+	connection := config.Config.Connections[connectionIndex]
+	// 已在标签页中打开的连接直接使用其环境
+	backupEnv := sessionEnv(connectionIndex)
+	opened := backupEnv == nil
+	if opened {
+		readOnly := connection
+		readOnly.ReadOnly = true
+		var err error
+		backupEnv, err = store.OpenEnv(readOnly)
+		if err != nil {
+			showErrorLog("Error opening LMDB database: " + err.Error())
+			return
+		}
+	}
+	closeEnv := func() {
+		if opened {
+			backupEnv.Close()
+		}
+	}
+	usedSize, err := store.UsedSize(backupEnv)
+	if err != nil {
+		closeEnv()
+		showErrorLog("Error reading LMDB environment info: " + err.Error())
+		return
+	}
+
+	progressBar := widget.NewProgressBar()
+	progressBar.Max = float64(usedSize)
+	progressLabel := widget.NewLabel("")
+	progressDialog := dialog.NewCustomWithoutButtons("Backing up", widget.NewForm(
+		widget.NewFormItem("Directory", widget.NewLabel(dir)),
+		widget.NewFormItem("Progress", progressBar),
+		widget.NewFormItem("", progressLabel),
+	), w)
+	progressDialog.Show()
+
+	go func() {
+		defer closeEnv()
+		done := make(chan error, 1)
+		go func(env *lmdb.Env) {
+			done <- store.Backup(env, dir, compact)
+		}(backupEnv)
+
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if info, err := os.Stat(filepath.Join(dir, store.DataFileName)); err == nil {
+					// 压缩复制的文件会小于已使用的大小，进度只作为估计
+					progressBar.SetValue(float64(min(info.Size(), usedSize)))
+					progressLabel.SetText(formatBytes(info.Size()) + " / " + formatBytes(usedSize))
+				}
+			case err := <-done:
+				progressDialog.Hide()
+				if err != nil {
+					showErrorLog("Error backing up: " + err.Error())
+					return
+				}
+				showInfoLog("Backup written to " + dir)
+				if register {
+					registerBackupConnection(connection, dir)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// registerBackupConnection 将备份添加为只读连接，沿用原连接的设置
+func registerBackupConnection(connection config.ConnectionConfig, dir string) {
+	backup := connection
+	backup.Name = connection.Name + " (backup " + time.Now().Format("2006-01-02 15:04") + ")"
+	backup.DatabasePath = dir
+	backup.ReadOnly = true
+	config.Config.Connections = append(config.Config.Connections, backup)
+	err := config.SaveConfig()
+	if err != nil {
+		showErrorLog("Error saving config: " + err.Error())
+	}
+	connectionList.Refresh()
+}
@@ -0,0 +1,246 @@
+// Package cli 实现不打开窗口的命令行模式，使用配置文件中的连接读写键值对
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 退出码
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage 表示参数错误，已经输出了用法说明
+var errUsage = errors.New("usage error")
+
+// command 为一个子命令，run 返回的错误会输出到标准错误
+type command struct {
+	name  string
+	args  string
+	help  string
+	flags func(fs *flag.FlagSet, o *options)
+	run   func(c *invocation, args []string) error
+	// noDB 表示命令不针对单个数据库，没有 --db 参数
+	noDB bool
+}
+
+var commands = []command{
+	{name: "get", args: "<connection> <key>", help: "print the value of a key (all values in a DupSort database)",
+		flags: func(fs *flag.FlagSet, o *options) { o.outputFlags(fs) }, run: runGet},
+	{name: "put", args: "<connection> <key> <value|->", help: "write a value (adds a duplicate in a DupSort database), - reads the value from stdin",
+		flags: func(fs *flag.FlagSet, o *options) { o.inputFlags(fs) }, run: runPut},
+	{name: "del", args: "<connection> <key>", help: "delete a key and all its values",
+		run: runDel},
+	{name: "scan", args: "<connection>", help: "list keys and values in key order",
+		flags: func(fs *flag.FlagSet, o *options) { o.outputFlags(fs); o.rangeFlags(fs) }, run: runScan},
+	{name: "stat", args: "<connection>", help: "print environment and database statistics",
+		flags: func(fs *flag.FlagSet, o *options) { fs.BoolVar(&o.json, "json", false, "print a JSON object") }, run: runStat},
+	{name: "list", args: "", help: "list configured connections and their named databases",
+		flags: func(fs *flag.FlagSet, o *options) { fs.BoolVar(&o.json, "json", false, "print JSON Lines") }, run: runList, noDB: true},
+	{name: "serve", args: "", help: "serve all connections over a local HTTP/JSON API",
+		flags: func(fs *flag.FlagSet, o *options) { o.serveFlags(fs) }, run: runServe, noDB: true},
+}
+
+// IsCommand 判断参数是否为命令行模式的子命令，main 据此决定是否打开窗口
+func IsCommand(name string) bool {
+	return findCommand(name) != nil || name == "help" || name == "-h" || name == "--help"
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// options 为子命令的参数
+type options struct {
+	db         string
+	json       bool
+	format     string
+	prefix     string
+	start, end string
+	descending bool
+	limit      int
+	addr       string
+	token      string
+}
+
+func (o *options) outputFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.json, "json", false, "print JSON Lines records {\"key\": ..., \"value\": ...}")
+	fs.StringVar(&o.format, "format", formatAuto, "value output format: "+strings.Join(outputFormats, ", "))
+}
+
+func (o *options) inputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", formatText, "value input format: "+strings.Join(inputFormats(), ", "))
+}
+
+func (o *options) rangeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.prefix, "prefix", "", "only keys with this prefix (in the connection's key layout)")
+	fs.StringVar(&o.start, "start", "", "first key, inclusive")
+	fs.StringVar(&o.end, "end", "", "end key, exclusive")
+	fs.BoolVar(&o.descending, "desc", false, "descending key order")
+	fs.IntVar(&o.limit, "limit", 0, "stop after this many records, 0 for no limit")
+}
+
+func (o *options) serveFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "addr", defaultServeAddr, "listen address")
+	fs.StringVar(&o.token, "token", "", "require \"Authorization: Bearer <token>\" on every request, defaults to $"+tokenEnv)
+}
+
+// invocation 为一次命令执行的参数和连接
+type invocation struct {
+	options
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	connection     config.ConnectionConfig
+	keyCodec       *codec.KeyCodec
+	protoSchema    *codec.ProtoSchema
+}
+
+// Run 执行命令行参数 args（不含程序名），返回进程的退出码
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	c := &invocation{stdin: stdin, stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: lmdb-gui-client %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	if !cmd.noDB {
+		fs.StringVar(&c.db, "db", "", "named database, the root database when empty")
+	}
+	if cmd.flags != nil {
+		cmd.flags(fs, &c.options)
+	}
+	positional, err := parseArgs(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	if err := config.LoadConfig(); err != nil {
+		fmt.Fprintln(stderr, "Error loading config: "+err.Error())
+		return exitError
+	}
+	err = cmd.run(c, positional)
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "Error: "+err.Error())
+		return exitError
+	}
+	return exitOK
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: lmdb-gui-client [command] [flags] [arguments]")
+	fmt.Fprintln(w, "\nWithout a command the GUI is started. Connections are looked up by name in lmdb-gui-client.yaml.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-5s %-28s %s\n", cmd.name, cmd.args, cmd.help)
+	}
+	fmt.Fprintln(w, "\nRun \"lmdb-gui-client <command> -h\" for the flags of a command.")
+}
+
+// parseArgs 解析参数，参数可以出现在位置参数之后，"--" 之后的都作为位置参数（例如以 - 开头的键）
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// loadConnection 按名称查找连接，并解析其键编码布局和 protobuf schema
+func (c *invocation) loadConnection(name string) error {
+	found := false
+	for _, connection := range config.Config.Connections {
+		if connection.Name == name {
+			c.connection, found = connection, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("connection %q not found", name)
+	}
+
+	var err error
+	if c.keyCodec, err = codec.ParseKeyCodec(c.connection.KeyLayout); err != nil {
+		return fmt.Errorf("invalid key layout: %w", err)
+	}
+	if len(c.connection.ProtoFiles) > 0 || len(c.connection.DescriptorSets) > 0 {
+		c.protoSchema, err = codec.LoadProtoSchema(c.connection.ProtoFiles, c.connection.ProtoImportPaths, c.connection.DescriptorSets)
+		if err != nil {
+			return fmt.Errorf("loading protobuf schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// openConnection 按名称查找连接并打开环境和数据库，write 为 false 时以只读方式打开
+func (c *invocation) openConnection(name string, write bool) (env *lmdb.Env, dbi lmdb.DBI, err error) {
+	if err = c.loadConnection(name); err != nil {
+		return nil, dbi, err
+	}
+	if write && c.connection.ReadOnly {
+		return nil, dbi, fmt.Errorf("connection %q is read-only", name)
+	}
+
+	// 只读的命令不获取写锁
+	connection := c.connection
+	connection.ReadOnly = !write
+	env, err = store.OpenEnv(connection)
+	if err != nil {
+		return nil, dbi, err
+	}
+	dbi, err = store.OpenDBI(env, c.db)
+	if err != nil {
+		env.Close()
+		return nil, dbi, err
+	}
+	return env, dbi, nil
+}
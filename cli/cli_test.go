@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/history"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// setupConnections 在临时目录中创建数据库，并配置可写的 test 连接和只读的 ro 连接。
+// 工作目录切换到临时目录，Run 不会读取仓库中的配置文件
+func setupConnections(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	saved := config.Config
+	t.Cleanup(func() {
+		config.Config = saved
+		os.Chdir(wd)
+	})
+
+	path := t.TempDir()
+	config.Config = config.AppConfig{Connections: []config.ConnectionConfig{
+		{Name: "test", DatabasePath: path, MapSize: 1, MaxDBs: 4},
+		{Name: "ro", DatabasePath: path, MapSize: 1, MaxDBs: 4, ReadOnly: true},
+	}}
+}
+
+func TestRun(t *testing.T) {
+	setupConnections(t)
+
+	// 按顺序执行，后面的命令读取前面写入的数据
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+	}{
+		{"put", []string{"put", "test", "a", "1"}, "", exitOK, ""},
+		{"put from stdin", []string{"put", "test", "multi", "-"}, "x\ny\tz", exitOK, ""},
+		{"put json", []string{"put", "test", "obj", `{"k": [1, 2]}`}, "", exitOK, ""},
+		{"put hex", []string{"put", "test", "bin", "00ff", "--format", "hex"}, "", exitOK, ""},
+		{"put invalid hex", []string{"put", "test", "bad", "zz", "--format", "hex"}, "", exitError, ""},
+		{"put read-only", []string{"put", "ro", "a", "2"}, "", exitError, ""},
+		{"put unknown connection", []string{"put", "nope", "a", "2"}, "", exitError, ""},
+		{"put missing value", []string{"put", "test", "a"}, "", exitUsage, ""},
+
+		{"get text", []string{"get", "test", "a", "--format", "text"}, "", exitOK, "1\n"},
+		{"get auto", []string{"get", "ro", "obj"}, "", exitOK, "{\"k\": [1, 2]}\n"},
+		{"get hex", []string{"get", "test", "bin", "--format", "hex"}, "", exitOK, "00ff\n"},
+		{"get raw", []string{"get", "test", "multi", "--format", "raw"}, "", exitOK, "x\ny\tz"},
+		{"get json", []string{"get", "test", "a", "--format", "text", "--json"}, "", exitOK, "{\"key\":\"a\",\"value\":\"1\"}\n"},
+		{"get raw json", []string{"get", "test", "bin", "--format", "raw", "--json"}, "", exitError, ""},
+		{"get unknown format", []string{"get", "test", "a", "--format", "nope"}, "", exitError, ""},
+		{"get missing", []string{"get", "test", "missing"}, "", exitError, ""},
+		{"get missing key argument", []string{"get", "test"}, "", exitUsage, ""},
+		{"get unknown flag", []string{"get", "test", "a", "--nope"}, "", exitUsage, ""},
+
+		{"scan", []string{"scan", "test", "--format", "text"}, "", exitOK,
+			"a\t1\nbin\t0x00ff\nmulti\t\"x\\ny\\tz\"\nobj\t{\"k\": [1, 2]}\n"},
+		{"scan prefix", []string{"scan", "ro", "--prefix", "m", "--json"}, "", exitOK, "{\"key\":\"multi\",\"value\":\"x\\ny\\tz\"}\n"},
+		{"scan range desc limit", []string{"scan", "test", "--start", "b", "--desc", "--limit", "2", "--format", "hex"}, "", exitOK,
+			"obj\t7b226b223a205b312c20325d7d\nmulti\t780a79097a\n"},
+		{"scan raw json", []string{"scan", "test", "--format", "raw", "--json"}, "", exitError, ""},
+		{"scan extra argument", []string{"scan", "test", "a"}, "", exitUsage, ""},
+
+		{"del", []string{"del", "test", "a"}, "", exitOK, ""},
+		{"del again", []string{"del", "test", "a"}, "", exitError, ""},
+		{"del read-only", []string{"del", "ro", "multi"}, "", exitError, ""},
+		{"get deleted", []string{"get", "test", "a"}, "", exitError, ""},
+
+		{"unknown command", []string{"nope"}, "", exitUsage, ""},
+		{"help", []string{"help"}, "", exitOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("Run(%q) = %d, want %d, stderr:\n%s", tt.args, code, tt.wantCode, stderr.String())
+			}
+			if tt.name != "help" && stdout.String() != tt.wantStdout {
+				t.Errorf("Run(%q) stdout = %q, want %q", tt.args, stdout.String(), tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	setupConnections(t)
+	for _, args := range [][]string{
+		{"put", "test", "a", "1"},
+		{"put", "test", "a", "2"},
+		{"del", "test", "a"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := Run(args, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+			t.Fatalf("Run(%q) = %d, stderr:\n%s", args, code, stderr.String())
+		}
+		if stderr.Len() > 0 {
+			t.Errorf("Run(%q) stderr:\n%s", args, stderr.String())
+		}
+	}
+
+	h, err := history.Open(config.Config.Connections[0].DatabasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind          store.ChangeKind
+		before, after string
+	}{
+		{store.ChangePut, "", "1"},
+		{store.ChangePut, "1", "2"},
+		{store.ChangeDelete, "2", ""},
+	}
+	if len(h.Entries) != len(want) {
+		t.Fatalf("history has %d entries, want %d", len(h.Entries), len(want))
+	}
+	for i, w := range want {
+		changes := h.Entries[i].Changes
+		if len(changes) != 1 {
+			t.Fatalf("entry %d has %d changes, want 1", i, len(changes))
+		}
+		c := changes[0]
+		if c.Kind != w.kind || string(c.Key) != "a" || joinValues(c.Before) != w.before || joinValues(c.After) != w.after {
+			t.Errorf("entry %d = %+v, want kind %v before %q after %q", i, c, w.kind, w.before, w.after)
+		}
+	}
+}
+
+func joinValues(values [][]byte) string {
+	return string(bytes.Join(values, []byte(",")))
+}
@@ -0,0 +1,357 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/history"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+var errLimit = errors.New("limit reached")
+
+func runGet(c *invocation, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	if err := c.checkOutputFormat(c.json); err != nil {
+		return err
+	}
+	env, dbi, err := c.openConnection(args[0], false)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+	key, err := c.keyCodec.Parse(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+
+	values, err := readValues(env, dbi, key)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("key %s not found", c.keyCodec.Format(key))
+	}
+	for _, val := range values {
+		if err := c.writeRecord(key, val, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPut(c *invocation, args []string) error {
+	if len(args) != 3 {
+		return errUsage
+	}
+	env, dbi, err := c.openConnection(args[0], true)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+	key, err := c.keyCodec.Parse(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	text := args[2]
+	if text == "-" {
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	return c.putValue(env, dbi, key, text)
+}
+
+func runDel(c *invocation, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	env, dbi, err := c.openConnection(args[0], true)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+	key, err := c.keyCodec.Parse(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+
+	found, err := c.deleteKey(env, dbi, key)
+	if err == nil && !found {
+		return fmt.Errorf("key %s not found", c.keyCodec.Format(key))
+	}
+	return err
+}
+
+func runScan(c *invocation, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	if err := c.checkOutputFormat(c.json); err != nil {
+		return err
+	}
+	env, dbi, err := c.openConnection(args[0], false)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+
+	keyRange, err := c.keyRange()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = env.View(func(txn *lmdb.Txn) error {
+		return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+			if c.limit > 0 && count >= c.limit {
+				return errLimit
+			}
+			count++
+			return c.writeRecord(key, val, true)
+		})
+	})
+	if errors.Is(err, errLimit) {
+		return nil
+	}
+	return err
+}
+
+// readValues 读取键的所有值，键不存在时返回空
+func readValues(env *lmdb.Env, dbi lmdb.DBI, key []byte) (values [][]byte, err error) {
+	err = env.View(func(txn *lmdb.Txn) (err error) {
+		values, err = store.ReadValues(txn, dbi, key)
+		return err
+	})
+	return values, err
+}
+
+// putValue 按 --format 解析文本并写入键，使用解码器编码时以键原来的第一个值保留类型和未知字段。
+// DupSort 数据库中为新增一个重复值，与窗口中的 Add Dup 相同（窗口中的 Update 替换选中的重复值）
+func (c *invocation) putValue(env *lmdb.Env, dbi lmdb.DBI, key []byte, text string) error {
+	var original []byte
+	values, err := readValues(env, dbi, key)
+	if err != nil {
+		return err
+	}
+	if len(values) > 0 {
+		original = values[0]
+	}
+	val, err := c.parseValue(key, text, original)
+	if err != nil {
+		return &valueError{err}
+	}
+	applied, err := store.ApplyChanges(env, dbi, []store.Change{{Kind: store.ChangePut, Key: key, Value: val}})
+	if err != nil {
+		return err
+	}
+	c.recordHistory(applied)
+	return nil
+}
+
+// valueError 表示输入的值不能按 --format 解析
+type valueError struct {
+	err error
+}
+
+func (e *valueError) Error() string {
+	return "invalid value: " + e.err.Error()
+}
+
+func (e *valueError) Unwrap() error {
+	return e.err
+}
+
+// deleteKey 删除键及其所有值，found 为 false 表示键不存在。
+// 删除前检查值未被修改，避免删除读取之后写入的值
+func (c *invocation) deleteKey(env *lmdb.Env, dbi lmdb.DBI, key []byte) (found bool, err error) {
+	values, err := readValues(env, dbi, key)
+	if err != nil || len(values) == 0 {
+		return false, err
+	}
+	applied, err := store.ApplyChanges(env, dbi, []store.Change{{Kind: store.ChangeDelete, Key: key, Before: values, Verify: true}})
+	if err != nil {
+		return true, err
+	}
+	c.recordHistory(applied)
+	return true, nil
+}
+
+// historyLock 使服务中并发的写请求依次读写历史文件
+var historyLock sync.Mutex
+
+// recordHistory 将已提交的修改加入连接的历史，与窗口中的写入一样可以在 History 页撤销。
+// 修改已经提交，保存历史失败时只输出警告
+func (c *invocation) recordHistory(changes []store.Change) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+	h, err := history.Open(c.connection.DatabasePath)
+	if err == nil {
+		err = h.Record(c.db, changes)
+	}
+	if err != nil && c.stderr != nil {
+		fmt.Fprintln(c.stderr, "Warning: saving history: "+err.Error())
+	}
+}
+
+// keyRange 按连接的键编码布局解析 --prefix、--start、--end
+func (c *invocation) keyRange() (keyRange store.KeyRange, err error) {
+	keyRange.Descending = c.descending
+	if keyRange.Prefix, err = c.keyCodec.ParsePrefix(c.prefix); err != nil {
+		return keyRange, fmt.Errorf("invalid prefix: %w", err)
+	}
+	if keyRange.Start, err = c.keyCodec.ParsePrefix(c.start); err != nil {
+		return keyRange, fmt.Errorf("invalid start: %w", err)
+	}
+	if keyRange.End, err = c.keyCodec.ParsePrefix(c.end); err != nil {
+		return keyRange, fmt.Errorf("invalid end: %w", err)
+	}
+	return keyRange, nil
+}
+
+// statField 为 stat 输出的一项
+type statField struct {
+	name  string
+	value interface{}
+}
+
+func runStat(c *invocation, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	env, dbi, err := c.openConnection(args[0], false)
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+	stats, err := store.ReadStats(env, dbi)
+	if err != nil {
+		return err
+	}
+
+	fields := c.statFields(stats)
+	if c.json {
+		data, err := fieldsJSON(fields)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", data)
+		return err
+	}
+	for _, field := range fields {
+		if _, err := fmt.Fprintf(c.stdout, "%s\t%v\n", field.name, field.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statFields 返回 stat 输出的各项，与窗口中统计页的内容相同
+func (c *invocation) statFields(stats *store.Stats) []statField {
+	mapSize := stats.Info.MapSize
+	if c.connection.MapSize > 0 {
+		mapSize = 1 << 30 * c.connection.MapSize
+	}
+	return []statField{
+		{"path", c.connection.DatabasePath},
+		{"database", c.db},
+		{"map_size", mapSize},
+		{"used_size", stats.UsedSize},
+		{"page_size", stats.Env.PSize},
+		{"last_page", stats.Info.LastPNO},
+		{"last_txn_id", stats.Info.LastTxnID},
+		{"readers", stats.Info.NumReaders},
+		{"max_readers", stats.Info.MaxReaders},
+		{"flags", store.FormatDBIFlags(stats.DBIFlags)},
+		{"entries", stats.DBI.Entries},
+		{"depth", stats.DBI.Depth},
+		{"branch_pages", stats.DBI.BranchPages},
+		{"leaf_pages", stats.DBI.LeafPages},
+		{"overflow_pages", stats.DBI.OverflowPages},
+	}
+}
+
+// fieldsJSON 按字段顺序输出 JSON 对象
+func fieldsJSON(fields []statField) ([]byte, error) {
+	buf := []byte{'{'}
+	for i, field := range fields {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		name, _ := json.Marshal(field.name)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, name...), ':'), value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// connectionInfo 为 list --json 输出的一个连接
+type connectionInfo struct {
+	Name      string   `json:"name"`
+	Path      string   `json:"path"`
+	ReadOnly  bool     `json:"read_only"`
+	Databases []string `json:"databases"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func runList(c *invocation, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	for _, connection := range config.Config.Connections {
+		info := connectionInfo{Name: connection.Name, Path: connection.DatabasePath, ReadOnly: connection.ReadOnly}
+		info.Databases, info.Error = listDatabases(connection)
+
+		if c.json {
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(c.stdout, "%s\n", data); err != nil {
+				return err
+			}
+			continue
+		}
+		line := info.Name + "\t" + info.Path
+		if info.Error != "" {
+			line += "\terror: " + info.Error
+		}
+		for _, name := range info.Databases {
+			line += "\t" + strconv.Quote(name)
+		}
+		if _, err := fmt.Fprintln(c.stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listDatabases 以只读方式打开连接并列出其中的命名数据库
+func listDatabases(connection config.ConnectionConfig) ([]string, string) {
+	connection.ReadOnly = true
+	env, err := store.OpenEnv(connection)
+	if err != nil {
+		return nil, err.Error()
+	}
+	defer env.Close()
+	names, err := store.ListDBINames(env, connection.MaxDBs)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return names, ""
+}
@@ -0,0 +1,478 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+const (
+	defaultServeAddr = "127.0.0.1:8765"
+	// tokenEnv 为未指定 --token 时读取令牌的环境变量，避免令牌出现在进程列表中
+	tokenEnv = "LMDB_GUI_CLIENT_TOKEN"
+
+	defaultScanLimit = 100
+	maxScanLimit     = 10000
+	// maxValueSize 为 PUT 请求体的大小上限
+	maxValueSize = 64 << 20
+)
+
+// apiError 为返回给客户端的错误及其 HTTP 状态码
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func newAPIError(status int, format string, args ...interface{}) error {
+	return &apiError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// serverConnection 为服务中一个已打开的连接。每个连接的环境只打开一次，由所有请求共用
+type serverConnection struct {
+	base invocation
+	env  *lmdb.Env
+	dbis map[string]lmdb.DBI
+}
+
+// server 通过 HTTP/JSON 接口提供配置文件中的连接
+type server struct {
+	token string
+	// stderr 用于输出保存历史失败等警告
+	stderr io.Writer
+
+	mu          sync.Mutex
+	connections map[string]*serverConnection
+}
+
+func runServe(c *invocation, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	token := c.token
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	s := &server{token: token, stderr: c.stderr, connections: make(map[string]*serverConnection)}
+	defer s.close()
+
+	listener, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	if token == "" && !isLoopback(listener.Addr()) {
+		fmt.Fprintln(c.stderr, "Warning: serving on a non-loopback address without a token")
+	}
+	fmt.Fprintf(c.stdout, "Serving %d connections on http://%s/api/\n", len(config.Config.Connections), listener.Addr())
+
+	httpServer := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// 等待进行中的请求结束后再关闭环境
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func isLoopback(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	return ok && tcpAddr.IP.IsLoopback()
+}
+
+// close 关闭所有打开的环境
+func (s *server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, connection := range s.connections {
+		connection.env.Close()
+		delete(s.connections, name)
+	}
+}
+
+// open 返回连接已打开的环境，第一次使用时打开。只读连接以 MDB_RDONLY 打开
+func (s *server) open(name string) (*serverConnection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if connection, ok := s.connections[name]; ok {
+		return connection, nil
+	}
+
+	connection := &serverConnection{base: invocation{stderr: s.stderr}, dbis: make(map[string]lmdb.DBI)}
+	if err := connection.base.loadConnection(name); err != nil {
+		if _, ok := findConnection(name); !ok {
+			return nil, newAPIError(http.StatusNotFound, "%s", err.Error())
+		}
+		return nil, err
+	}
+	env, err := store.OpenEnv(connection.base.connection)
+	if err != nil {
+		return nil, err
+	}
+	connection.env = env
+	s.connections[name] = connection
+	return connection, nil
+}
+
+func findConnection(name string) (config.ConnectionConfig, bool) {
+	for _, connection := range config.Config.Connections {
+		if connection.Name == name {
+			return connection, true
+		}
+	}
+	return config.ConnectionConfig{}, false
+}
+
+// dbi 返回连接中已打开的数据库，数据库的句柄在环境关闭前一直有效
+func (s *server) dbi(connection *serverConnection, name string) (lmdb.DBI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dbi, ok := connection.dbis[name]; ok {
+		return dbi, nil
+	}
+	dbi, err := store.OpenDBI(connection.env, name)
+	if lmdb.IsNotFound(err) {
+		return dbi, newAPIError(http.StatusNotFound, "database %q not found", name)
+	}
+	if err != nil {
+		return dbi, err
+	}
+	connection.dbis[name] = dbi
+	return dbi, nil
+}
+
+// ServeHTTP 校验令牌并按路径分发请求：
+//
+//	GET    /api/connections
+//	GET    /api/connections/{name}/databases
+//	GET    /api/connections/{name}/stats?db=
+//	GET    /api/connections/{name}/keys?db=&prefix=&start=&end=&desc=&limit=&cursor=&format=
+//	GET    /api/connections/{name}/keys/{key}?db=&format=
+//	PUT    /api/connections/{name}/keys/{key}?db=&format=
+//	DELETE /api/connections/{name}/keys/{key}?db=
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, newAPIError(http.StatusUnauthorized, "missing or invalid bearer token"))
+			return
+		}
+	}
+
+	// 路径中的每一段分别解码，键中的 / 需要写为 %2F
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			writeError(w, newAPIError(http.StatusBadRequest, "invalid path: %s", err))
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+	if len(segments) < 2 || segments[0] != "api" || segments[1] != "connections" {
+		writeError(w, newAPIError(http.StatusNotFound, "not found"))
+		return
+	}
+	segments = segments[2:]
+
+	var handler func(w http.ResponseWriter, r *http.Request, segments []string) error
+	methods := []string{http.MethodGet}
+	switch {
+	case len(segments) == 0:
+		handler = s.handleConnections
+	case len(segments) == 2 && segments[1] == "databases":
+		handler = s.handleDatabases
+	case len(segments) == 2 && segments[1] == "stats":
+		handler = s.handleStats
+	case len(segments) == 2 && segments[1] == "keys":
+		handler = s.handleScan
+	case len(segments) == 3 && segments[1] == "keys":
+		handler = s.handleKey
+		methods = []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+	default:
+		writeError(w, newAPIError(http.StatusNotFound, "not found"))
+		return
+	}
+	if !slices.Contains(methods, r.Method) {
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeError(w, newAPIError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	if err := handler(w, r, segments); err != nil {
+		writeError(w, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// writeError 返回 {"error": "..."}，未知的错误返回 500
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	var conflict *store.ConflictError
+	var valueErr *valueError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	case errors.As(err, &conflict):
+		status = http.StatusConflict
+	case errors.As(err, &valueErr):
+		status = http.StatusBadRequest
+	case errors.As(err, &tooLarge):
+		status = http.StatusRequestEntityTooLarge
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *server) handleConnections(w http.ResponseWriter, r *http.Request, segments []string) error {
+	infos := make([]connectionInfo, 0, len(config.Config.Connections))
+	for _, connection := range config.Config.Connections {
+		info := connectionInfo{Name: connection.Name, Path: connection.DatabasePath, ReadOnly: connection.ReadOnly}
+		if opened, err := s.open(connection.Name); err != nil {
+			info.Error = err.Error()
+		} else if info.Databases, err = store.ListDBINames(opened.env, connection.MaxDBs); err != nil {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+	}
+	writeJSON(w, http.StatusOK, infos)
+	return nil
+}
+
+func (s *server) handleDatabases(w http.ResponseWriter, r *http.Request, segments []string) error {
+	connection, err := s.open(segments[0])
+	if err != nil {
+		return err
+	}
+	names, err := store.ListDBINames(connection.env, connection.base.connection.MaxDBs)
+	if err != nil {
+		return err
+	}
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, names)
+	return nil
+}
+
+// request 解析请求的连接、数据库和参数，返回本次请求使用的 invocation
+func (s *server) request(r *http.Request, name string, write bool) (*invocation, *lmdb.Env, lmdb.DBI, error) {
+	var dbi lmdb.DBI
+	if connection, ok := findConnection(name); ok && write && connection.ReadOnly {
+		return nil, nil, dbi, newAPIError(http.StatusForbidden, "connection %q is read-only", name)
+	}
+	connection, err := s.open(name)
+	if err != nil {
+		return nil, nil, dbi, err
+	}
+
+	query := r.URL.Query()
+	c := connection.base
+	c.db = query.Get("db")
+	c.format = query.Get("format")
+	if c.format == "" {
+		c.format = formatAuto
+		if write {
+			c.format = formatText
+		}
+	}
+	if !write {
+		// 响应为 JSON，不能使用 raw 格式
+		if err := c.checkOutputFormat(true); err != nil {
+			return nil, nil, dbi, newAPIError(http.StatusBadRequest, "%s", err.Error())
+		}
+	}
+	c.prefix, c.start, c.end = query.Get("prefix"), query.Get("start"), query.Get("end")
+	if desc := query.Get("desc"); desc != "" {
+		if c.descending, err = strconv.ParseBool(desc); err != nil {
+			return nil, nil, dbi, newAPIError(http.StatusBadRequest, "invalid desc %q", desc)
+		}
+	}
+	c.limit = defaultScanLimit
+	if limit := query.Get("limit"); limit != "" {
+		if c.limit, err = strconv.Atoi(limit); err != nil || c.limit <= 0 || c.limit > maxScanLimit {
+			return nil, nil, dbi, newAPIError(http.StatusBadRequest, "limit must be between 1 and %d", maxScanLimit)
+		}
+	}
+
+	dbi, err = s.dbi(connection, c.db)
+	if err != nil {
+		return nil, nil, dbi, err
+	}
+	return &c, connection.env, dbi, nil
+}
+
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request, segments []string) error {
+	c, env, dbi, err := s.request(r, segments[0], false)
+	if err != nil {
+		return err
+	}
+	stats, err := store.ReadStats(env, dbi)
+	if err != nil {
+		return err
+	}
+	data, err := fieldsJSON(c.statFields(stats))
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, json.RawMessage(data))
+	return nil
+}
+
+// scanResponse 为一页扫描结果，NextCursor 不为空时作为 cursor 参数读取下一页
+type scanResponse struct {
+	Records    []record `json:"records"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// handleScan 按键的顺序读取一页记录。limit 为键的数量，DupSort 数据库中一个键的所有值总在同一页中
+func (s *server) handleScan(w http.ResponseWriter, r *http.Request, segments []string) error {
+	c, env, dbi, err := s.request(r, segments[0], false)
+	if err != nil {
+		return err
+	}
+	keyRange, err := c.keyRange()
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "%s", err.Error())
+	}
+	// cursor 为上一页最后一个键的原始字节，从它之后继续
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, "invalid cursor")
+		}
+		if keyRange.Descending {
+			if len(keyRange.End) == 0 || bytes.Compare(after, keyRange.End) < 0 {
+				keyRange.End = after
+			}
+		} else if next := append(after, 0); bytes.Compare(next, keyRange.Start) > 0 {
+			keyRange.Start = next
+		}
+	}
+
+	response := scanResponse{Records: []record{}}
+	var lastKey []byte
+	keys := 0
+	err = env.View(func(txn *lmdb.Txn) error {
+		return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+			if !bytes.Equal(key, lastKey) {
+				if keys == c.limit {
+					response.NextCursor = base64.RawURLEncoding.EncodeToString(lastKey)
+					return errLimit
+				}
+				keys++
+				lastKey = append(lastKey[:0], key...)
+			}
+			rec, err := c.newRecord(key, val)
+			if err != nil {
+				return err
+			}
+			response.Records = append(response.Records, rec)
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		return err
+	}
+	writeJSON(w, http.StatusOK, response)
+	return nil
+}
+
+// keyResponse 为一个键的所有值（非 DupSort 数据库只有一个）
+type keyResponse struct {
+	Key    string        `json:"key"`
+	Values []interface{} `json:"values"`
+}
+
+func (s *server) handleKey(w http.ResponseWriter, r *http.Request, segments []string) error {
+	write := r.Method != http.MethodGet
+	c, env, dbi, err := s.request(r, segments[0], write)
+	if err != nil {
+		return err
+	}
+	key, err := c.keyCodec.Parse(segments[2])
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "invalid key: %s", err)
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		// 请求体为值，按 format 参数解析，默认原样写入
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValueSize))
+		if err != nil {
+			return err
+		}
+		if err := c.putValue(env, dbi, key, string(data)); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		found, err := c.deleteKey(env, dbi, key)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return newAPIError(http.StatusNotFound, "key %s not found", c.keyCodec.Format(key))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		values, err := readValues(env, dbi, key)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return newAPIError(http.StatusNotFound, "key %s not found", c.keyCodec.Format(key))
+		}
+		response := keyResponse{Key: c.keyCodec.Format(key)}
+		for _, val := range values {
+			rec, err := c.newRecord(key, val)
+			if err != nil {
+				return err
+			}
+			response.Values = append(response.Values, rec.Value)
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/history"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+const testToken = "secret"
+
+// newTestServer 返回要求令牌的服务，只读连接使用单独的目录，两个环境不会在同一进程中打开同一个文件
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+	setupConnections(t)
+	config.Config.Connections[1].DatabasePath = t.TempDir()
+	s := &server{token: testToken, connections: make(map[string]*serverConnection)}
+	t.Cleanup(s.close)
+	return s
+}
+
+// serve 发送一个带令牌的请求，返回响应
+func serve(s *server, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestServerAuth(t *testing.T) {
+	s := newTestServer(t)
+	for _, tt := range []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"wrong token", "Bearer nope"},
+		{"wrong scheme", "Basic " + testToken},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/connections", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			if w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+	if w := serve(s, http.MethodGet, "/api/connections", ""); w.Code != http.StatusOK {
+		t.Errorf("with token: status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestServerMethods(t *testing.T) {
+	s := newTestServer(t)
+	for _, tt := range []struct {
+		method, target string
+		want           int
+	}{
+		{http.MethodPut, "/api/connections/test/keys/a", http.StatusNoContent},
+		{http.MethodGet, "/api/connections/test/keys/a", http.StatusOK},
+		{"PU", "/api/connections/test/keys/a", http.StatusMethodNotAllowed},
+		{"ELETE", "/api/connections/test/keys/a", http.StatusMethodNotAllowed},
+		{http.MethodPatch, "/api/connections/test/keys/a", http.StatusMethodNotAllowed},
+		{http.MethodPut, "/api/connections/test/keys", http.StatusMethodNotAllowed},
+		{"G", "/api/connections", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/api/connections/test/keys/a", http.StatusNoContent},
+		{http.MethodDelete, "/api/connections/test/keys/a", http.StatusNotFound},
+		{http.MethodGet, "/api/connections/nope/keys/a", http.StatusNotFound},
+	} {
+		w := serve(s, tt.method, tt.target, "1")
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.target, w.Code, tt.want, w.Body)
+		}
+		if w.Code == http.StatusMethodNotAllowed && w.Header().Get("Allow") == "" {
+			t.Errorf("%s %s: missing Allow header", tt.method, tt.target)
+		}
+	}
+}
+
+func TestServerReadOnly(t *testing.T) {
+	s := newTestServer(t)
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		w := serve(s, method, "/api/connections/ro/keys/a", "1")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s on read-only connection: status = %d, want %d: %s", method, w.Code, http.StatusForbidden, w.Body)
+		}
+	}
+}
+
+func TestServerEscapedKey(t *testing.T) {
+	s := newTestServer(t)
+	if w := serve(s, http.MethodPut, "/api/connections/test/keys/a%2Fb%20c", "v"); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT: status = %d: %s", w.Code, w.Body)
+	}
+	w := serve(s, http.MethodGet, "/api/connections/test/keys/a%2Fb%20c?format=text", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET: status = %d: %s", w.Code, w.Body)
+	}
+	var got keyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != "a/b c" || len(got.Values) != 1 || got.Values[0] != "v" {
+		t.Errorf("GET = %+v, want key \"a/b c\" with value v", got)
+	}
+	// 未转义的 / 分隔路径，不是键的一部分
+	if w := serve(s, http.MethodGet, "/api/connections/test/keys/a/b%20c", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET with unescaped /: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestServerScanPaging(t *testing.T) {
+	s := newTestServer(t)
+	keys := []string{"k1", "k2", "k3", "k4", "k5", "x"}
+	for _, key := range keys {
+		if w := serve(s, http.MethodPut, "/api/connections/test/keys/"+key, "v"+key); w.Code != http.StatusNoContent {
+			t.Fatalf("PUT %s: status = %d: %s", key, w.Code, w.Body)
+		}
+	}
+
+	for _, tt := range []struct {
+		name  string
+		query string
+		want  [][]string
+	}{
+		{"ascending", "prefix=k&limit=2", [][]string{{"k1", "k2"}, {"k3", "k4"}, {"k5"}}},
+		{"descending", "prefix=k&limit=2&desc=true", [][]string{{"k5", "k4"}, {"k3", "k2"}, {"k1"}}},
+		{"exact pages", "limit=3", [][]string{{"k1", "k2", "k3"}, {"k4", "k5", "x"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := ""
+			for i, want := range tt.want {
+				target := "/api/connections/test/keys?format=text&" + tt.query
+				if cursor != "" {
+					target += "&cursor=" + url.QueryEscape(cursor)
+				}
+				w := serve(s, http.MethodGet, target, "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("page %d: status = %d: %s", i, w.Code, w.Body)
+				}
+				var page scanResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+					t.Fatal(err)
+				}
+				var got []string
+				for _, rec := range page.Records {
+					got = append(got, rec.Key)
+				}
+				if strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("page %d = %v, want %v", i, got, want)
+				}
+				cursor = page.NextCursor
+				last := i == len(tt.want)-1
+				if last != (cursor == "") {
+					t.Fatalf("page %d: next_cursor = %q", i, cursor)
+				}
+			}
+		})
+	}
+
+	if w := serve(s, http.MethodGet, "/api/connections/test/keys?cursor=!", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServerFormat(t *testing.T) {
+	s := newTestServer(t)
+	if w := serve(s, http.MethodPut, "/api/connections/test/keys/a", "1"); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT: status = %d: %s", w.Code, w.Body)
+	}
+	for _, target := range []string{
+		"/api/connections/test/keys/a?format=nope",
+		"/api/connections/test/keys/a?format=raw",
+		"/api/connections/test/keys?format=raw",
+		"/api/connections/test/stats?format=nope",
+	} {
+		if w := serve(s, http.MethodGet, target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want %d: %s", target, w.Code, http.StatusBadRequest, w.Body)
+		}
+	}
+	if w := serve(s, http.MethodPut, "/api/connections/test/keys/a?format=nope", "1"); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with unknown format: status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+}
+
+func TestServerHistory(t *testing.T) {
+	s := newTestServer(t)
+	if w := serve(s, http.MethodPut, "/api/connections/test/keys/a", "1"); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT: status = %d: %s", w.Code, w.Body)
+	}
+	if w := serve(s, http.MethodDelete, "/api/connections/test/keys/a", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE: status = %d: %s", w.Code, w.Body)
+	}
+	h, err := history.Open(config.Config.Connections[0].DatabasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Entries) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(h.Entries))
+	}
+	if c := h.Entries[1].Changes; len(c) != 1 || c[0].Kind != store.ChangeDelete || joinValues(c[0].Before) != "1" {
+		t.Errorf("DELETE entry = %+v, want delete of value 1", c)
+	}
+}
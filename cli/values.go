@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+)
+
+// 值的格式
+const (
+	// formatAuto 与值面板相同：能识别的格式解码为 JSON，其余 UTF-8 文本原样输出，二进制数据输出为 0x 加十六进制
+	formatAuto = "auto"
+	// formatText 按 UTF-8 文本读写，输出时非 UTF-8 的数据显示为 0x 加十六进制
+	formatText = "text"
+	// formatRaw 原样输出值的字节
+	formatRaw    = "raw"
+	formatHex    = "hex"
+	formatBase64 = "base64"
+	// formatProto 按连接中键前缀映射的 protobuf 消息类型编码
+	formatProto = "proto"
+)
+
+var outputFormats = []string{formatAuto, formatText, formatRaw, formatHex, formatBase64}
+
+// inputFormats 返回 put 支持的值格式，包括所有值解码器的名称
+func inputFormats() []string {
+	formats := []string{formatText, formatHex, formatBase64, formatProto}
+	for _, decoder := range codec.Decoders() {
+		formats = append(formats, fmt.Sprintf("%q", decoder.Name()))
+	}
+	return formats
+}
+
+// protoDecoder 返回与键前缀最长匹配的消息类型的解码器，没有匹配时返回 nil
+func (c *invocation) protoDecoder(key []byte) (codec.ValueDecoder, error) {
+	if c.protoSchema == nil {
+		return nil, nil
+	}
+	message := ""
+	matched := -1
+	for _, mapping := range c.connection.ProtoMappings {
+		if bytes.HasPrefix(key, []byte(mapping.KeyPrefix)) && len(mapping.KeyPrefix) > matched {
+			message = mapping.Message
+			matched = len(mapping.KeyPrefix)
+		}
+	}
+	if matched < 0 {
+		return nil, nil
+	}
+	return c.protoSchema.Decoder(message)
+}
+
+// checkOutputFormat 在读取前检查 --format。JSON 字符串只能保存 UTF-8 文本，原样输出的二进制数据会被改写，
+// 所以 JSON 输出不能使用 raw 格式
+func (c *invocation) checkOutputFormat(jsonOutput bool) error {
+	if !slices.Contains(outputFormats, c.format) {
+		return fmt.Errorf("unknown output format %q, use one of %s", c.format, strings.Join(outputFormats, ", "))
+	}
+	if jsonOutput && c.format == formatRaw {
+		return errors.New("the raw format cannot be used with JSON output, use hex or base64")
+	}
+	return nil
+}
+
+// formatValue 按 --format 将值转换为输出的文本，isJSON 表示文本为解码得到的 JSON
+func (c *invocation) formatValue(key, val []byte) (text string, isJSON bool, err error) {
+	switch c.format {
+	case formatAuto:
+		decoder, err := c.protoDecoder(key)
+		if err != nil {
+			return "", false, err
+		}
+		if decoder == nil || !decoder.Detect(val) {
+			decoder = codec.DetectDecoder(val)
+		}
+		if decoder != nil {
+			if decoded, err := decoder.Decode(val); err == nil {
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, []byte(decoded)); err == nil {
+					return compact.String(), true, nil
+				}
+			}
+		}
+		return codec.DisplayString(val), false, nil
+	case formatText:
+		return codec.DisplayString(val), false, nil
+	case formatRaw:
+		return string(val), false, nil
+	case formatHex:
+		return hex.EncodeToString(val), false, nil
+	case formatBase64:
+		return base64.StdEncoding.EncodeToString(val), false, nil
+	}
+	return "", false, fmt.Errorf("unknown output format %q, use one of %s", c.format, strings.Join(outputFormats, ", "))
+}
+
+// parseValue 按 --format 将输入的文本转换为值，original 为键原来的值，使用解码器编码时用于保留原有的类型和未知字段
+func (c *invocation) parseValue(key []byte, text string, original []byte) ([]byte, error) {
+	switch c.format {
+	case formatText:
+		return []byte(text), nil
+	case formatHex:
+		return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(text), "0x"))
+	case formatBase64:
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	case formatProto:
+		decoder, err := c.protoDecoder(key)
+		if err != nil {
+			return nil, err
+		}
+		if decoder == nil {
+			return nil, fmt.Errorf("no protobuf mapping matches key %s", c.keyCodec.Format(key))
+		}
+		return decoder.Encode(text, original)
+	}
+	for _, decoder := range codec.Decoders() {
+		if strings.EqualFold(decoder.Name(), c.format) {
+			return decoder.Encode(text, original)
+		}
+	}
+	return nil, fmt.Errorf("unknown input format %q, use one of %s", c.format, strings.Join(inputFormats(), ", "))
+}
+
+// record 为 --json 输出的一条记录
+type record struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// newRecord 按 --format 创建一条记录，解码得到的 JSON 直接嵌入，其余为字符串
+func (c *invocation) newRecord(key, val []byte) (record, error) {
+	text, isJSON, err := c.formatValue(key, val)
+	if err != nil {
+		return record{}, err
+	}
+	r := record{Key: c.keyCodec.Format(key), Value: text}
+	if isJSON {
+		r.Value = json.RawMessage(text)
+	}
+	return r, nil
+}
+
+// writeRecord 输出一条记录。JSON Lines 中解码得到的 JSON 直接嵌入，其余为字符串；
+// 普通输出中 withKey 为 true 时输出 "键<Tab>值"，包含换行或 Tab 的键和值按 Go 字符串转义，每条记录占一行；否则只输出值
+func (c *invocation) writeRecord(key, val []byte, withKey bool) error {
+	if c.json {
+		r, err := c.newRecord(key, val)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "%s\n", data)
+		return err
+	}
+	text, _, err := c.formatValue(key, val)
+	if err != nil {
+		return err
+	}
+	if withKey {
+		_, err = fmt.Fprintf(c.stdout, "%s\t%s\n", lineField(c.keyCodec.Format(key)), lineField(text))
+		return err
+	}
+	if c.format == formatRaw {
+		// 原样输出时不追加换行，便于重定向到文件
+		_, err = io.WriteString(c.stdout, text)
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, text)
+	return err
+}
+
+// lineField 在文本包含换行、Tab 或以双引号开头时按 Go 字符串转义并加上双引号，其余原样返回
+func lineField(text string) string {
+	if strings.ContainsAny(text, "\t\n\r") || strings.HasPrefix(text, `"`) {
+		return strconv.Quote(text)
+	}
+	return text
+}
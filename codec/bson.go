@@ -0,0 +1,38 @@
+package codec
+
+import (
+	"encoding/binary"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BSON 解码器，使用 MongoDB Extended JSON（relaxed 模式）显示和编辑
+type bsonDecoder struct{}
+
+func (bsonDecoder) Name() string {
+	return "BSON"
+}
+
+func (bsonDecoder) Detect(data []byte) bool {
+	// BSON 文档以小端 int32 的总长度开头，并以 0x00 结尾
+	if len(data) < 5 || int(binary.LittleEndian.Uint32(data)) != len(data) || data[len(data)-1] != 0 {
+		return false
+	}
+	return bson.Raw(data).Validate() == nil
+}
+
+func (bsonDecoder) Decode(data []byte) (string, error) {
+	text, err := bson.MarshalExtJSONIndent(bson.Raw(data), false, false, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+func (bsonDecoder) Encode(text string, original []byte) ([]byte, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(text), false, &doc); err != nil {
+		return nil, err
+	}
+	return bson.Marshal(doc)
+}
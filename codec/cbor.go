@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR 标签在 JSON 中表示为 {"$tag": 编号, "$value": 内容}
+const (
+	cborTagKey   = "$tag"
+	cborValueKey = "$value"
+)
+
+// CBOR 解码器
+type cborDecoder struct{}
+
+func (cborDecoder) Name() string {
+	return "CBOR"
+}
+
+func (cborDecoder) Detect(data []byte) bool {
+	// 只识别顶层为数组、map 或标签的数据
+	major := data[0] >> 5
+	if major != 4 && major != 5 && major != 6 {
+		return false
+	}
+	return cbor.Wellformed(data) == nil
+}
+
+func (cborDecoder) Decode(data []byte) (string, error) {
+	var v interface{}
+	if err := cbor.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	return marshalIndent(fromCBORValue(v))
+}
+
+func (cborDecoder) Encode(text string, original []byte) ([]byte, error) {
+	v, err := unmarshalJSON(text)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := cbor.EncOptions{Sort: cbor.SortCoreDeterministic}.EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return mode.Marshal(toCBORValue(v))
+}
+
+// fromCBORValue 将 CBOR 标签转换为 JSON 中的表示方式
+func fromCBORValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case cbor.Tag:
+		return map[string]interface{}{cborTagKey: v.Number, cborValueKey: fromCBORValue(v.Content)}
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			m[k] = fromCBORValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = fromCBORValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// toCBORValue 是 fromCBORValue 的逆操作
+func toCBORValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if number, ok := v[cborTagKey]; ok && len(v) == 2 {
+			if content, ok := v[cborValueKey]; ok {
+				var n uint64
+				switch number := number.(type) {
+				case int64:
+					n = uint64(number)
+				case uint64:
+					n = number
+				}
+				return cbor.Tag{Number: n, Content: toCBORValue(content)}
+			}
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = toCBORValue(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, e := range v {
+			m[k] = toCBORValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = toCBORValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
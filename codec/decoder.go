@@ -0,0 +1,269 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValueDecoder 将某种序列化格式的值在原始字节和可编辑的 JSON 文本之间转换
+type ValueDecoder interface {
+	// Name 返回显示在值面板中的格式名称
+	Name() string
+	// Detect 判断数据是否为该格式，用于自动选择解码器
+	Detect(data []byte) bool
+	// Decode 将数据解码为格式化的 JSON 文本
+	Decode(data []byte) (string, error)
+	// Encode 将编辑后的 JSON 文本重新编码为原始格式，original 为编辑前的数据
+	Encode(text string, original []byte) ([]byte, error)
+}
+
+var errTrailingData = errors.New("trailing data after value")
+
+// 内置解码器，自动识别时按此顺序尝试
+var decoders = []ValueDecoder{
+	bsonDecoder{},
+	gobDecoder{},
+	msgpackDecoder{},
+	cborDecoder{},
+	protoRawDecoder{},
+}
+
+// RegisterDecoder 注册一个值解码器，自动识别时按注册顺序尝试
+func RegisterDecoder(decoder ValueDecoder) {
+	for i, d := range decoders {
+		if d.Name() == decoder.Name() {
+			decoders[i] = decoder
+			return
+		}
+	}
+	decoders = append(decoders, decoder)
+}
+
+// Decoders 返回所有已注册的解码器
+func Decoders() []ValueDecoder {
+	return decoders
+}
+
+// DecoderByName 按名称查找解码器
+func DecoderByName(name string) ValueDecoder {
+	for _, d := range decoders {
+		if d.Name() == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// DetectDecoder 返回第一个能识别数据的解码器，都无法识别时返回 nil
+func DetectDecoder(data []byte) ValueDecoder {
+	if len(data) == 0 {
+		return nil
+	}
+	for _, d := range decoders {
+		if d.Detect(data) {
+			return d
+		}
+	}
+	return nil
+}
+
+// base64Key 是二进制数据在 JSON 中的表示方式：{"$base64": "..."}
+const base64Key = "$base64"
+
+// mapKey 是键不全为字符串的 map 在 JSON 中的表示方式：{"$map": [[键, 值], ...]}，
+// 重新编码时键保持原来的类型，例如 CBOR、MessagePack 中的整数键
+const mapKey = "$map"
+
+// toJSONValue 将解码得到的通用值转换为可以序列化为 JSON 的值
+func toJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return map[string]interface{}{base64Key: base64.StdEncoding.EncodeToString(v)}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = toJSONValue(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			key, ok := k.(string)
+			if !ok {
+				return mapEntries(v)
+			}
+			m[key] = toJSONValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = toJSONValue(e)
+		}
+		return s
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+		return jsonFloat(v)
+	case float32:
+		return toJSONValue(float64(v))
+	default:
+		return v
+	}
+}
+
+// mapEntries 将键不全为字符串的 map 转换为 {"$map": [[键, 值], ...]}
+func mapEntries(m map[interface{}]interface{}) map[string]interface{} {
+	keys := sortedKeys(m)
+	entries := make([]interface{}, len(keys))
+	for i, k := range keys {
+		entries[i] = []interface{}{toJSONValue(k), toJSONValue(m[k])}
+	}
+	return map[string]interface{}{mapKey: entries}
+}
+
+// sortedKeys 返回 map 的所有键，数字键在前按大小排列，其他键按文本排列
+func sortedKeys(m map[interface{}]interface{}) []interface{} {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, aNumber := keyNumber(keys[i])
+		b, bNumber := keyNumber(keys[j])
+		if aNumber != bNumber {
+			return aNumber
+		}
+		if aNumber && a != b {
+			return a < b
+		}
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+// keyNumber 返回整数或浮点数键的值，用于排序
+func keyNumber(k interface{}) (float64, bool) {
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// jsonFloat 保证整数值的浮点数输出为 "2.0" 而不是 "2"，重新编码时仍然是浮点数
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	text := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if !strings.ContainsAny(text, ".eE") {
+		text += ".0"
+	}
+	return []byte(text), nil
+}
+
+// fromJSONValue 是 toJSONValue 的逆操作，整数会被还原为 int64 或 uint64
+func fromJSONValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		if s, ok := v[base64Key].(string); ok && len(v) == 1 {
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return b
+			}
+		}
+		if entries, ok := v[mapKey].([]interface{}); ok && len(v) == 1 {
+			if m, ok := fromMapEntries(entries); ok {
+				return m
+			}
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = fromJSONValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = fromJSONValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// fromMapEntries 是 mapEntries 的逆操作，entries 中每一项必须为 [键, 值]
+func fromMapEntries(entries []interface{}) (map[interface{}]interface{}, bool) {
+	m := make(map[interface{}]interface{}, len(entries))
+	for _, entry := range entries {
+		pair, ok := entry.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, false
+		}
+		key := fromJSONValue(pair[0])
+		// 二进制键以字符串保存，数组和 map 不能作为 Go map 的键
+		if b, ok := key.([]byte); ok {
+			key = string(b)
+		}
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, false
+		}
+		m[key] = fromJSONValue(pair[1])
+	}
+	return m, true
+}
+
+// marshalIndent 将通用值格式化为 JSON 文本，map 的键按字母顺序输出
+func marshalIndent(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(toJSONValue(v)); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
+
+// unmarshalJSON 解析编辑后的 JSON 文本为通用值
+func unmarshalJSON(text string) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return fromJSONValue(v), nil
+}
+
+// DecoderNames 返回所有解码器的名称，按注册顺序排列
+func DecoderNames() []string {
+	names := make([]string, len(decoders))
+	for i, d := range decoders {
+		names[i] = d.Name()
+	}
+	return names
+}
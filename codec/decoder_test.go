@@ -0,0 +1,123 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+type gobRecord struct {
+	Name   string
+	Scores map[int]string
+	Data   []byte
+}
+
+// TestDecoderRoundTrip 检查不修改内容时解码再编码得到原来的字节，exact 为 false 的格式只要求再次解码得到相同的文本
+func TestDecoderRoundTrip(t *testing.T) {
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(gobRecord{Name: "a", Scores: map[int]string{1: "x", 20: "y"}, Data: []byte{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	bsonData, err := bson.Marshal(bson.D{{Key: "a", Value: int32(1)}, {Key: "b", Value: bson.A{"x", 2.5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var protoData []byte
+	protoData = protowire.AppendTag(protoData, 1, protowire.VarintType)
+	protoData = protowire.AppendVarint(protoData, 150)
+	protoData = protowire.AppendTag(protoData, 2, protowire.BytesType)
+	protoData = protowire.AppendString(protoData, "hello")
+
+	tests := []struct {
+		name    string
+		decoder ValueDecoder
+		data    []byte
+		exact   bool
+	}{
+		// {1: "x"}
+		{"cbor integer key", cborDecoder{}, mustHex(t, "a1016178"), true},
+		// {"a": 1, "b": [1.5, h'00ff'], -2: 1(0)}
+		{"cbor mixed keys", cborDecoder{}, mustHex(t, "a3616101616282f93e004200ff21c100"), false},
+		// {"a": 1, "b": [true, null]}
+		{"cbor string keys", cborDecoder{}, mustHex(t, "a2616101616282f5f6"), true},
+		// {1: "x"}
+		{"msgpack integer key", msgpackDecoder{}, mustHex(t, "8101a178"), true},
+		// {2: {"k": [1, 2]}, "a": 1.5}
+		{"msgpack mixed keys", msgpackDecoder{}, mustHex(t, "820281a16b920102a161cb3ff8000000000000"), true},
+		{"bson", bsonDecoder{}, bsonData, true},
+		{"gob", gobDecoder{}, gobData.Bytes(), false},
+		{"protobuf raw", protoRawDecoder{}, protoData, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.decoder.Decode(tt.data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			encoded, err := tt.decoder.Encode(text, tt.data)
+			if err != nil {
+				t.Fatalf("Encode(%s): %v", text, err)
+			}
+			if tt.exact && !bytes.Equal(encoded, tt.data) {
+				t.Errorf("Encode(%s) = %x, want %x", text, encoded, tt.data)
+			}
+			again, err := tt.decoder.Decode(encoded)
+			if err != nil {
+				t.Fatalf("Decode(%x): %v", encoded, err)
+			}
+			if again != text {
+				t.Errorf("round trip changed the value:\n%s\nwant\n%s", again, text)
+			}
+		})
+	}
+}
+
+// TestIntegerKeysAfterEdit 检查编辑值之后整数键仍然是整数
+func TestIntegerKeysAfterEdit(t *testing.T) {
+	tests := []struct {
+		name    string
+		decoder ValueDecoder
+		want    string
+	}{
+		// {1: "y", 2: {"$base64": "AP8="}}
+		{"cbor", cborDecoder{}, "a2016179024200ff"},
+		{"msgpack", msgpackDecoder{}, "8201a17902c40200ff"},
+	}
+	text := `{"$map": [[2, {"$base64": "AP8="}], [1, "y"]]}`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.decoder.Encode(text, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(encoded); got != tt.want {
+				t.Errorf("Encode = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectMsgpackBinaryKey 检查二进制键的 map 不会在识别格式时出错
+func TestDetectMsgpackBinaryKey(t *testing.T) {
+	// {h'00': 1}
+	data := mustHex(t, "81c4010001")
+	if !(msgpackDecoder{}).Detect(data) {
+		t.Fatal("map with a binary key not detected")
+	}
+	if _, err := (msgpackDecoder{}).Decode(data); err != nil {
+		t.Fatal(err)
+	}
+}
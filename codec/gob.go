@@ -0,0 +1,334 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Go gob 解码器。gob 数据流中带有完整的类型定义，解码时根据类型定义动态构造
+// 对应的 Go 类型，再交给 encoding/gob 解码，因此不需要事先注册具体类型
+type gobDecoder struct{}
+
+// 以下结构与 encoding/gob 内部描述类型定义的 wireType 保持一致，gob 按字段名匹配
+type gobCommonType struct {
+	Name string
+	Id   int
+}
+
+type gobArrayType struct {
+	CommonType gobCommonType
+	Elem       int
+	Len        int
+}
+
+type gobSliceType struct {
+	CommonType gobCommonType
+	Elem       int
+}
+
+type gobFieldType struct {
+	Name string
+	Id   int
+}
+
+type gobStructType struct {
+	CommonType gobCommonType
+	Field      []gobFieldType
+}
+
+type gobMapType struct {
+	CommonType gobCommonType
+	Key        int
+	Elem       int
+}
+
+type gobEncoderType struct {
+	CommonType gobCommonType
+}
+
+type gobWireType struct {
+	ArrayT           *gobArrayType
+	SliceT           *gobSliceType
+	StructT          *gobStructType
+	MapT             *gobMapType
+	GobEncoderT      *gobEncoderType
+	BinaryMarshalerT *gobEncoderType
+	TextMarshalerT   *gobEncoderType
+}
+
+// gob 内置类型的 ID
+const (
+	gobWireTypeID  = 16
+	gobFirstUserID = 64
+)
+
+var gobBuiltinTypes = map[int]reflect.Type{
+	1: reflect.TypeOf(false),
+	2: reflect.TypeOf(int64(0)),
+	3: reflect.TypeOf(uint64(0)),
+	4: reflect.TypeOf(float64(0)),
+	5: reflect.TypeOf([]byte(nil)),
+	6: reflect.TypeOf(""),
+	7: reflect.TypeOf(complex128(0)),
+	8: reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// 实现了 GobEncoder、BinaryMarshaler、TextMarshaler 的类型在数据流中只有编码后的字节，
+// 使用以下类型原样保存
+type gobEncodedValue []byte
+
+func (v gobEncodedValue) GobEncode() ([]byte, error) { return v, nil }
+
+func (v *gobEncodedValue) GobDecode(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+type gobBinaryValue []byte
+
+func (v gobBinaryValue) MarshalBinary() ([]byte, error) { return v, nil }
+
+func (v *gobBinaryValue) UnmarshalBinary(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+type gobTextValue string
+
+func (v gobTextValue) MarshalText() ([]byte, error) { return []byte(v), nil }
+
+func (v *gobTextValue) UnmarshalText(data []byte) error {
+	*v = gobTextValue(data)
+	return nil
+}
+
+func (gobDecoder) Name() string {
+	return "Gob"
+}
+
+func (d gobDecoder) Detect(data []byte) bool {
+	_, err := d.unmarshal(data)
+	return err == nil
+}
+
+func (d gobDecoder) Decode(data []byte) (string, error) {
+	v, err := d.unmarshal(data)
+	if err != nil {
+		return "", err
+	}
+	text, err := json.MarshalIndent(v.Interface(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+func (gobDecoder) Encode(text string, original []byte) ([]byte, error) {
+	t, err := gobValueType(original)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal([]byte(text), v.Interface()); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).EncodeValue(v.Elem()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobDecoder) unmarshal(data []byte) (v reflect.Value, err error) {
+	t, err := gobValueType(data)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ptr := reflect.New(t)
+	reader := bytes.NewReader(data)
+	if err := gob.NewDecoder(reader).DecodeValue(ptr); err != nil {
+		return reflect.Value{}, err
+	}
+	if reader.Len() != 0 {
+		return reflect.Value{}, errTrailingData
+	}
+	return ptr.Elem(), nil
+}
+
+// gobValueType 读取数据流中的类型定义，返回第一个值对应的 Go 类型
+func gobValueType(data []byte) (t reflect.Type, err error) {
+	defer func() {
+		// reflect.StructOf 等函数遇到非法定义时会 panic
+		if r := recover(); r != nil {
+			t, err = nil, fmt.Errorf("gob: %v", r)
+		}
+	}()
+
+	builder := gobTypeBuilder{wireTypes: make(map[int]gobWireType), types: make(map[int]reflect.Type)}
+	for len(data) > 0 {
+		length, n, err := gobReadUint(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		if length == 0 || length > uint64(len(data)) {
+			return nil, errors.New("gob: invalid message length")
+		}
+		message := data[:length]
+		data = data[length:]
+
+		id, n, err := gobReadInt(message)
+		if err != nil {
+			return nil, err
+		}
+		if id >= 0 {
+			return builder.typeOf(int(id))
+		}
+
+		// 类型定义：把消息改写为内置 wireType 类型的值，交给 encoding/gob 解码
+		var rewritten bytes.Buffer
+		payload := append(gobAppendInt(nil, gobWireTypeID), message[n:]...)
+		rewritten.Write(gobAppendUint(nil, uint64(len(payload))))
+		rewritten.Write(payload)
+		var wire gobWireType
+		if err := gob.NewDecoder(&rewritten).Decode(&wire); err != nil {
+			return nil, err
+		}
+		builder.wireTypes[int(-id)] = wire
+	}
+	return nil, errors.New("gob: no value in data")
+}
+
+type gobTypeBuilder struct {
+	wireTypes map[int]gobWireType
+	types     map[int]reflect.Type
+	building  map[int]bool
+}
+
+func (b *gobTypeBuilder) typeOf(id int) (reflect.Type, error) {
+	if t, ok := gobBuiltinTypes[id]; ok {
+		return t, nil
+	}
+	if t, ok := b.types[id]; ok {
+		return t, nil
+	}
+	wire, ok := b.wireTypes[id]
+	if !ok || id < gobFirstUserID {
+		return nil, fmt.Errorf("gob: unknown type id %d", id)
+	}
+	if b.building == nil {
+		b.building = make(map[int]bool)
+	}
+	if b.building[id] {
+		return nil, errors.New("gob: recursive types are not supported")
+	}
+	b.building[id] = true
+	defer delete(b.building, id)
+
+	var t reflect.Type
+	switch {
+	case wire.ArrayT != nil:
+		elem, err := b.typeOf(wire.ArrayT.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = reflect.ArrayOf(wire.ArrayT.Len, elem)
+	case wire.SliceT != nil:
+		elem, err := b.typeOf(wire.SliceT.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = reflect.SliceOf(elem)
+	case wire.MapT != nil:
+		key, err := b.typeOf(wire.MapT.Key)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := b.typeOf(wire.MapT.Elem)
+		if err != nil {
+			return nil, err
+		}
+		t = reflect.MapOf(key, elem)
+	case wire.StructT != nil:
+		fields := make([]reflect.StructField, 0, len(wire.StructT.Field))
+		for _, field := range wire.StructT.Field {
+			fieldType, err := b.typeOf(field.Id)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, reflect.StructField{Name: field.Name, Type: fieldType})
+		}
+		t = reflect.StructOf(fields)
+	case wire.GobEncoderT != nil:
+		t = reflect.TypeOf(gobEncodedValue(nil))
+	case wire.BinaryMarshalerT != nil:
+		t = reflect.TypeOf(gobBinaryValue(nil))
+	case wire.TextMarshalerT != nil:
+		t = reflect.TypeOf(gobTextValue(""))
+	default:
+		return nil, fmt.Errorf("gob: empty type definition %d", id)
+	}
+	b.types[id] = t
+	return t, nil
+}
+
+// gobReadUint 按 gob 的编码规则读取无符号整数：小于 128 的值占一个字节，
+// 否则第一个字节为字节数的相反数，后跟大端序的数值
+func gobReadUint(data []byte) (uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errors.New("gob: unexpected end of data")
+	}
+	b := data[0]
+	if b < 0x80 {
+		return uint64(b), 1, nil
+	}
+	n := -int(int8(b))
+	if n > 8 || len(data) < n+1 {
+		return 0, 0, errors.New("gob: invalid unsigned integer")
+	}
+	var x uint64
+	for _, c := range data[1 : n+1] {
+		x = x<<8 | uint64(c)
+	}
+	return x, n + 1, nil
+}
+
+func gobReadInt(data []byte) (int64, int, error) {
+	u, n, err := gobReadUint(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	if u&1 != 0 {
+		return ^int64(u >> 1), n, nil
+	}
+	return int64(u >> 1), n, nil
+}
+
+func gobAppendUint(data []byte, x uint64) []byte {
+	if x < 0x80 {
+		return append(data, byte(x))
+	}
+	var buf [8]byte
+	n := 8
+	for x > 0 {
+		n--
+		buf[n] = byte(x)
+		x >>= 8
+	}
+	data = append(data, byte(-(8 - n)))
+	return append(data, buf[n:]...)
+}
+
+func gobAppendInt(data []byte, x int64) []byte {
+	var u uint64
+	if x < 0 {
+		u = uint64(^x<<1) | 1
+	} else {
+		u = uint64(x << 1)
+	}
+	return gobAppendUint(data, u)
+}
@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// IsText 判断值是否可以作为文本安全地显示和编辑
+func IsText(data []byte) bool {
+	return utf8.Valid(data)
+}
+
+// HexDump 以 "偏移量  十六进制字节  |ASCII|" 的格式输出数据，与 hexdump -C 相同
+func HexDump(data []byte) string {
+	return hex.Dump(data)
+}
+
+// ParseHexDump 将 HexDump 的输出（可能经过编辑）解析回原始字节。
+// 每行开头的 8 位偏移量和 "|" 之后的 ASCII 栏会被忽略，其余部分必须是十六进制字节
+func ParseHexDump(dump string) ([]byte, error) {
+	data := make([]byte, 0, len(dump)/4)
+	for lineNo, line := range strings.Split(dump, "\n") {
+		if i := strings.IndexByte(line, '|'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 0 && len(fields[0]) == 8 {
+			// 偏移量
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			b, err := hex.DecodeString(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid hex %q", lineNo+1, field)
+			}
+			data = append(data, b...)
+		}
+	}
+	return data, nil
+}
+
+// DisplayString 返回适合在表格中显示的字符串，非 UTF-8 数据显示为十六进制
+func DisplayString(data []byte) string {
+	if IsText(data) {
+		return string(data)
+	}
+	return "0x" + hex.EncodeToString(data)
+}
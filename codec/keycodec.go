@@ -0,0 +1,228 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// DefaultKeyLayout 为键编码的默认布局，键按 UTF-8 文本显示和输入
+const DefaultKeyLayout = "utf8"
+
+// KeyLayoutSeparator 分隔组合布局中的各个部分，同时也是组合键显示和输入时各字段的分隔符
+const KeyLayoutSeparator = "|"
+
+// keyPartKinds 为可用的键字段类型，size 为 0 表示变长字段
+var keyPartKinds = map[string]keyPartKind{
+	"utf8":   {size: 0},
+	"hex":    {size: 0},
+	"u32be":  {size: 4},
+	"u32le":  {size: 4},
+	"u64be":  {size: 8},
+	"u64le":  {size: 8},
+	"uuid":   {size: 16},
+	"uint32": {size: 4, alias: "u32be"},
+	"uint64": {size: 8, alias: "u64be"},
+}
+
+type keyPartKind struct {
+	size  int
+	alias string
+}
+
+// KeyLayouts 返回常用的键编码布局，供界面下拉选择
+func KeyLayouts() []string {
+	return []string{"utf8", "hex", "u32be", "u32le", "u64be", "u64le", "uuid", "u32be|utf8", "u64be|utf8"}
+}
+
+// KeyCodec 按布局在键的原始字节和可读文本之间转换。
+// 布局由若干字段组成，用 "|" 分隔，例如 "u32be|utf8"；变长字段（utf8、hex）只能出现在最后
+type KeyCodec struct {
+	layout string
+	parts  []string
+}
+
+// ParseKeyCodec 解析键编码布局，空布局等同于 utf8
+func ParseKeyCodec(layout string) (*KeyCodec, error) {
+	layout = strings.TrimSpace(layout)
+	if layout == "" {
+		layout = DefaultKeyLayout
+	}
+	names := strings.Split(layout, KeyLayoutSeparator)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		kind, ok := keyPartKinds[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown key part %q", name)
+		}
+		if kind.alias != "" {
+			name = kind.alias
+		}
+		if kind.size == 0 && i != len(names)-1 {
+			return nil, fmt.Errorf("variable-length key part %q must be the last part", name)
+		}
+		parts[i] = name
+	}
+	return &KeyCodec{layout: strings.Join(parts, KeyLayoutSeparator), parts: parts}, nil
+}
+
+// String 返回规范化后的布局
+func (c *KeyCodec) String() string {
+	return c.layout
+}
+
+// IsText 表示键按原样作为 UTF-8 文本处理
+func (c *KeyCodec) IsText() bool {
+	return c.layout == DefaultKeyLayout
+}
+
+// Format 将键格式化为文本，不符合布局的键显示为 "0x" 加十六进制
+func (c *KeyCodec) Format(key []byte) string {
+	if c.IsText() {
+		return DisplayString(key)
+	}
+	fields := make([]string, 0, len(c.parts))
+	rest := key
+	for _, part := range c.parts {
+		size := keyPartKinds[part].size
+		if size == 0 {
+			size = len(rest)
+		}
+		if len(rest) < size {
+			return rawKeyString(key)
+		}
+		field, ok := formatKeyPart(part, rest[:size])
+		if !ok {
+			return rawKeyString(key)
+		}
+		fields = append(fields, field)
+		rest = rest[size:]
+	}
+	if len(rest) > 0 {
+		return rawKeyString(key)
+	}
+	return strings.Join(fields, KeyLayoutSeparator)
+}
+
+// Parse 将文本解析为完整的键。非 utf8 布局下，"0x" 开头的文本按原始十六进制解析
+func (c *KeyCodec) Parse(text string) ([]byte, error) {
+	key, fields, err := c.parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if fields < len(c.parts) {
+		return nil, fmt.Errorf("key %q has %d of %d parts of layout %s", text, fields, len(c.parts), c.layout)
+	}
+	return key, nil
+}
+
+// ParsePrefix 将文本解析为键前缀，允许只给出布局的前几个字段
+func (c *KeyCodec) ParsePrefix(text string) ([]byte, error) {
+	if text == "" {
+		return nil, nil
+	}
+	key, _, err := c.parse(text)
+	return key, err
+}
+
+func (c *KeyCodec) parse(text string) ([]byte, int, error) {
+	if c.IsText() {
+		return []byte(text), len(c.parts), nil
+	}
+	if strings.HasPrefix(text, "0x") {
+		key, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid hex key %q", text)
+		}
+		return key, len(c.parts), nil
+	}
+	// 最后一个字段可能是包含分隔符的文本，因此最多切分为布局的字段数
+	fields := strings.SplitN(text, KeyLayoutSeparator, len(c.parts))
+	key := make([]byte, 0, len(text))
+	for i, field := range fields {
+		b, err := parseKeyPart(c.parts[i], field)
+		if err != nil {
+			return nil, 0, fmt.Errorf("key part %d (%s): %w", i+1, c.parts[i], err)
+		}
+		key = append(key, b...)
+	}
+	return key, len(fields), nil
+}
+
+func rawKeyString(key []byte) string {
+	return "0x" + hex.EncodeToString(key)
+}
+
+func formatKeyPart(part string, data []byte) (string, bool) {
+	switch part {
+	case "utf8":
+		if !utf8.Valid(data) {
+			return "", false
+		}
+		return string(data), true
+	case "hex":
+		return hex.EncodeToString(data), true
+	case "u32be":
+		return strconv.FormatUint(uint64(binary.BigEndian.Uint32(data)), 10), true
+	case "u32le":
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10), true
+	case "u64be":
+		return strconv.FormatUint(binary.BigEndian.Uint64(data), 10), true
+	case "u64le":
+		return strconv.FormatUint(binary.LittleEndian.Uint64(data), 10), true
+	case "uuid":
+		id, err := uuid.FromBytes(data)
+		if err != nil {
+			return "", false
+		}
+		return id.String(), true
+	}
+	return "", false
+}
+
+func parseKeyPart(part string, field string) ([]byte, error) {
+	switch part {
+	case "utf8":
+		return []byte(field), nil
+	case "hex":
+		return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(field), "0x"))
+	case "u32be", "u32le":
+		n, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 4)
+		if part == "u32be" {
+			binary.BigEndian.PutUint32(b, uint32(n))
+		} else {
+			binary.LittleEndian.PutUint32(b, uint32(n))
+		}
+		return b, nil
+	case "u64be", "u64le":
+		n, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 8)
+		if part == "u64be" {
+			binary.BigEndian.PutUint64(b, n)
+		} else {
+			binary.LittleEndian.PutUint64(b, n)
+		}
+		return b, nil
+	case "uuid":
+		id, err := uuid.Parse(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		return id[:], nil
+	}
+	return nil, errors.New("unknown key part")
+}
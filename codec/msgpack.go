@@ -0,0 +1,135 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack 解码器
+type msgpackDecoder struct{}
+
+func (msgpackDecoder) Name() string {
+	return "MessagePack"
+}
+
+func (d msgpackDecoder) Detect(data []byte) bool {
+	// 只识别顶层为 map 或数组的数据，避免把普通文本误判为 MessagePack
+	b := data[0]
+	if !(b >= 0x80 && b <= 0x9f) && b != 0xdc && b != 0xdd && b != 0xde && b != 0xdf {
+		return false
+	}
+	_, err := d.unmarshal(data)
+	return err == nil
+}
+
+func (d msgpackDecoder) Decode(data []byte) (string, error) {
+	v, err := d.unmarshal(data)
+	if err != nil {
+		return "", err
+	}
+	return marshalIndent(v)
+}
+
+func (msgpackDecoder) Encode(text string, original []byte) ([]byte, error) {
+	v, err := unmarshalJSON(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+	if err := encoder.Encode(toMsgpackValue(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshal 解码完整的数据，存在多余字节时返回错误
+func (msgpackDecoder) unmarshal(data []byte) (interface{}, error) {
+	reader := bytes.NewReader(data)
+	decoder := msgpack.NewDecoder(reader)
+	decoder.SetMapDecoder(decodeMsgpackMap)
+	v, err := decoder.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	if reader.Len() != 0 {
+		return nil, errTrailingData
+	}
+	return v, nil
+}
+
+// decodeMsgpackMap 解码任意类型键的 map，默认的解码方式只支持字符串键。
+// 二进制键转换为字符串，数组和 map 不能作为键
+func decodeMsgpackMap(d *msgpack.Decoder) (interface{}, error) {
+	n, err := d.DecodeMapLen()
+	if err != nil || n == -1 {
+		return nil, err
+	}
+	// n 来自数据本身，不按它预先分配过大的空间
+	m := make(map[interface{}]interface{}, min(n, 1024))
+	for i := 0; i < n; i++ {
+		k, err := d.DecodeInterface()
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := k.([]byte); ok {
+			k = string(b)
+		}
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, fmt.Errorf("msgpack: unsupported map key of type %T", k)
+		}
+		if m[k], err = d.DecodeInterface(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// msgpackMap 按 sortedKeys 的顺序编码，使相同的内容总是得到相同的字节
+type msgpackMap map[interface{}]interface{}
+
+func (m msgpackMap) EncodeMsgpack(e *msgpack.Encoder) error {
+	if err := e.EncodeMapLen(len(m)); err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(m) {
+		if err := e.Encode(k); err != nil {
+			return err
+		}
+		if err := e.Encode(m[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toMsgpackValue 将非字符串键的 map 转换为 msgpackMap
+func toMsgpackValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(msgpackMap, len(v))
+		for k, e := range v {
+			m[k] = toMsgpackValue(e)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = toMsgpackValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = toMsgpackValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
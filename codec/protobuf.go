@@ -0,0 +1,205 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtoSchema 保存从 .proto 文件和 FileDescriptorSet 文件中加载的消息类型
+type ProtoSchema struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// LoadProtoSchema 编译 .proto 文件并读取 FileDescriptorSet 文件。
+// importPaths 为空时使用每个 .proto 文件所在的目录作为导入路径
+func LoadProtoSchema(protoFiles, importPaths, descriptorSets []string) (*ProtoSchema, error) {
+	files := new(protoregistry.Files)
+
+	for _, path := range descriptorSets {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		setFiles, err := protodesc.NewFiles(&set)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		setFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			// 多个文件中重复定义的描述符以先加载的为准
+			_ = files.RegisterFile(fd)
+			return true
+		})
+	}
+
+	for _, path := range protoFiles {
+		paths := importPaths
+		name := path
+		if len(paths) == 0 {
+			paths = []string{filepath.Dir(path)}
+			name = filepath.Base(path)
+		} else {
+			for _, importPath := range paths {
+				if rel, err := filepath.Rel(importPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+					name = filepath.ToSlash(rel)
+					break
+				}
+			}
+		}
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: paths}),
+		}
+		compiled, err := compiler.Compile(context.Background(), name)
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range compiled {
+			registerFileWithDeps(files, fd)
+		}
+	}
+
+	return &ProtoSchema{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// registerFileWithDeps 注册文件及其依赖的文件，已注册的文件会被跳过
+func registerFileWithDeps(files *protoregistry.Files, fd protoreflect.FileDescriptor) {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		registerFileWithDeps(files, imports.Get(i).FileDescriptor)
+	}
+	_ = files.RegisterFile(fd)
+}
+
+// MessageNames 返回 schema 中所有消息类型的全名
+func (s *ProtoSchema) MessageNames() []string {
+	names := make([]string, 0)
+	s.files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		collectMessageNames(fd.Messages(), &names)
+		return true
+	})
+	return names
+}
+
+func collectMessageNames(messages protoreflect.MessageDescriptors, names *[]string) {
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		*names = append(*names, string(md.FullName()))
+		collectMessageNames(md.Messages(), names)
+	}
+}
+
+// Decoder 返回指定消息类型的解码器
+func (s *ProtoSchema) Decoder(message string) (ValueDecoder, error) {
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(message))
+	if err != nil {
+		return nil, fmt.Errorf("protobuf message %s: %w", message, err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a protobuf message", message)
+	}
+	return protoDecoder{schema: s, message: md}, nil
+}
+
+// protoDecoder 按指定的消息类型在 protobuf 二进制格式和 JSON 之间转换
+type protoDecoder struct {
+	schema  *ProtoSchema
+	message protoreflect.MessageDescriptor
+}
+
+func (d protoDecoder) Name() string {
+	return "Protobuf"
+}
+
+func (d protoDecoder) Detect(data []byte) bool {
+	return proto.Unmarshal(data, dynamicpb.NewMessage(d.message)) == nil
+}
+
+func (d protoDecoder) Decode(data []byte) (string, error) {
+	msg := dynamicpb.NewMessage(d.message)
+	if err := (proto.UnmarshalOptions{Resolver: d.schema.types}).Unmarshal(data, msg); err != nil {
+		return "", err
+	}
+	text, err := protojson.MarshalOptions{Resolver: d.schema.types}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+	// protojson 的输出格式不稳定，重新缩进后再显示
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, text, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Encode 将 JSON 重新编码为 protobuf，编辑前数据中的未知字段会被保留
+func (d protoDecoder) Encode(text string, original []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(d.message)
+	if err := (protojson.UnmarshalOptions{Resolver: d.schema.types}).Unmarshal([]byte(text), msg); err != nil {
+		return nil, err
+	}
+	if len(original) > 0 {
+		originalMsg := dynamicpb.NewMessage(d.message)
+		if err := (proto.UnmarshalOptions{Resolver: d.schema.types}).Unmarshal(original, originalMsg); err == nil {
+			copyUnknownFields(originalMsg, msg)
+		}
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+// copyUnknownFields 将 src 中的未知字段复制到 dst 中对应的位置，包括嵌套的消息
+func copyUnknownFields(src, dst protoreflect.Message) {
+	if unknown := src.GetUnknown(); len(unknown) > 0 {
+		dst.SetUnknown(append(dst.GetUnknown(), unknown...))
+	}
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || !dst.Has(fd) {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			srcList, dstList := v.List(), dst.Mutable(fd).List()
+			for i := 0; i < srcList.Len() && i < dstList.Len(); i++ {
+				copyUnknownFields(srcList.Get(i).Message(), dstList.Get(i).Message())
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			dstMap := dst.Mutable(fd).Map()
+			v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				if dstMap.Has(key) {
+					copyUnknownFields(value.Message(), dstMap.Mutable(key).Message())
+				}
+				return true
+			})
+		default:
+			copyUnknownFields(v.Message(), dst.Mutable(fd).Message())
+		}
+		return true
+	})
+}
@@ -0,0 +1,203 @@
+package codec
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 不依赖 schema 的 protobuf 解码器，按字段编号和 wire type 显示原始结构。
+// 每个字段显示为 {"field": 编号, "type": wire type, ...}，length-delimited 字段根据内容
+// 显示为 "string"、"message"（嵌套消息）或 "base64"
+type protoRawDecoder struct{}
+
+// ProtoRawDecoderName 是不依赖 schema 的 protobuf 解码器的名称
+const ProtoRawDecoderName = "Protobuf (raw)"
+
+func (protoRawDecoder) Name() string {
+	return ProtoRawDecoderName
+}
+
+// Detect 总是返回 false：几乎任何数据都能被解析为某种 wire 格式，不参与自动识别
+func (protoRawDecoder) Detect(data []byte) bool {
+	return false
+}
+
+func (protoRawDecoder) Decode(data []byte) (string, error) {
+	fields, err := decodeRawProto(data)
+	if err != nil {
+		return "", err
+	}
+	return marshalIndent(fields)
+}
+
+func (protoRawDecoder) Encode(text string, original []byte) ([]byte, error) {
+	v, err := unmarshalJSON(text)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("raw protobuf must be a JSON array of fields")
+	}
+	return encodeRawProto(nil, fields)
+}
+
+func decodeRawProto(data []byte) ([]interface{}, error) {
+	fields := make([]interface{}, 0)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		field := map[string]interface{}{"field": int64(num)}
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field["type"] = "varint"
+			field["value"] = v
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field["type"] = "fixed32"
+			field["value"] = uint64(v)
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field["type"] = "fixed64"
+			field["value"] = v
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			field["type"] = "bytes"
+			if isPrintable(v) {
+				field["string"] = string(v)
+			} else if nested, err := decodeRawProto(v); err == nil && len(nested) > 0 {
+				field["message"] = nested
+			} else {
+				field["base64"] = base64.StdEncoding.EncodeToString(v)
+			}
+		case protowire.StartGroupType:
+			v, n := protowire.ConsumeGroup(num, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			nested, err := decodeRawProto(v)
+			if err != nil {
+				return nil, err
+			}
+			field["type"] = "group"
+			field["message"] = nested
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d", typ)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func encodeRawProto(data []byte, fields []interface{}) ([]byte, error) {
+	for i, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field #%d is not an object", i+1)
+		}
+		num, ok := toUint64(field["field"])
+		if !ok || !protowire.Number(num).IsValid() {
+			return nil, fmt.Errorf("field #%d: invalid field number", i+1)
+		}
+		typ, _ := field["type"].(string)
+		switch typ {
+		case "varint", "fixed32", "fixed64":
+			v, ok := toUint64(field["value"])
+			if !ok {
+				return nil, fmt.Errorf("field %d: invalid %s value", num, typ)
+			}
+			switch typ {
+			case "varint":
+				data = protowire.AppendTag(data, protowire.Number(num), protowire.VarintType)
+				data = protowire.AppendVarint(data, v)
+			case "fixed32":
+				data = protowire.AppendTag(data, protowire.Number(num), protowire.Fixed32Type)
+				data = protowire.AppendFixed32(data, uint32(v))
+			default:
+				data = protowire.AppendTag(data, protowire.Number(num), protowire.Fixed64Type)
+				data = protowire.AppendFixed64(data, v)
+			}
+		case "bytes":
+			var v []byte
+			switch {
+			case field["string"] != nil:
+				s, _ := field["string"].(string)
+				v = []byte(s)
+			case field["message"] != nil:
+				nested, _ := field["message"].([]interface{})
+				var err error
+				if v, err = encodeRawProto(nil, nested); err != nil {
+					return nil, err
+				}
+			default:
+				s, _ := field["base64"].(string)
+				var err error
+				if v, err = base64.StdEncoding.DecodeString(s); err != nil {
+					return nil, fmt.Errorf("field %d: %w", num, err)
+				}
+			}
+			data = protowire.AppendTag(data, protowire.Number(num), protowire.BytesType)
+			data = protowire.AppendBytes(data, v)
+		case "group":
+			nested, _ := field["message"].([]interface{})
+			data = protowire.AppendTag(data, protowire.Number(num), protowire.StartGroupType)
+			var err error
+			if data, err = encodeRawProto(data, nested); err != nil {
+				return nil, err
+			}
+			data = protowire.AppendTag(data, protowire.Number(num), protowire.EndGroupType)
+		default:
+			return nil, fmt.Errorf("field %d: unknown type %q", num, typ)
+		}
+	}
+	return data, nil
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	return 0, false
+}
+
+// isPrintable 判断字节是否为可打印的 UTF-8 文本
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+	"github.com/zshimonz/lmdb-gui-client/compare"
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 比较结果的最大数量，达到后停止比较
+const maxCompareResults = 10000
+
+// compareTarget 为比较的一侧：连接下标和数据库名
+type compareTarget struct {
+	connection int
+	dbiName    string
+}
+
+var compareTabItem *fyne.Container
+var compareList *widget.List
+var compareSummaryLabel *widget.Label
+var compareLeftEntry *widget.Entry
+var compareRightEntry *widget.Entry
+
+// compareResults 为比较得到的所有差异，compareVisible 为按类型过滤后显示的差异在 compareResults 中的下标
+var compareResults []compare.Difference
+var compareVisible []int
+var compareChecked = make(map[int]bool)
+var compareShowKinds = map[compare.Kind]bool{compare.OnlyLeft: true, compare.OnlyRight: true, compare.Changed: true}
+
+// compareLock 保护 compareResults、compareVisible 和 compareShowKinds，比较协程会替换结果，
+// 列表的回调可能在绘制协程中调用；持有期间不要调用控件的方法
+var compareLock sync.Mutex
+var compareSides [2]compareTarget
+var compareConnectionSelects []*widget.Select
+var compareKeyCodec *codec.KeyCodec
+
+// compareEnvs 缓存比较时打开的其他连接的环境，同一个进程中不能重复打开同一个环境
+var compareEnvs = make(map[int]*lmdb.Env)
+
+// compareGeneration 每次开始或停止比较时递增，旧的比较发现编号变化后不再写入结果
+var compareGeneration atomic.Int64
+var compareCancel context.CancelFunc
+var compareDone chan struct{}
+
+func initCompareTabItem(w fyne.Window) *fyne.Container {
+	leftConnection, leftDBI := newCompareSideSelects()
+	rightConnection, rightDBI := newCompareSideSelects()
+	prefixEntry := widget.NewEntry()
+	prefixEntry.SetPlaceHolder("Key prefix filter (left connection's key layout)")
+
+	compareSummaryLabel = widget.NewLabel("Select two databases and click Compare")
+	compareList = widget.NewList(
+		func() int {
+			compareLock.Lock()
+			defer compareLock.Unlock()
+			return len(compareVisible)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			index, diff, ok := visibleCompareResult(i)
+			if !ok {
+				return
+			}
+			row := o.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%-10s %s", diff.Kind, compareKeyCodec.Format(diff.Key)))
+			check := row.Objects[1].(*widget.Check)
+			check.OnChanged = nil
+			check.SetChecked(compareChecked[index])
+			check.OnChanged = func(b bool) {
+				compareChecked[index] = b
+			}
+		},
+	)
+	compareLeftEntry = widget.NewMultiLineEntry()
+	compareLeftEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	compareLeftEntry.Disable()
+	compareRightEntry = widget.NewMultiLineEntry()
+	compareRightEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	compareRightEntry.Disable()
+	compareList.OnSelected = func(id widget.ListItemID) {
+		_, diff, ok := visibleCompareResult(id)
+		if !ok {
+			return
+		}
+		compareLeftEntry.SetText(formatDiffValues(diff.Left))
+		compareRightEntry.SetText(formatDiffValues(diff.Right))
+	}
+	compareList.OnUnselected = func(id widget.ListItemID) {
+		compareLeftEntry.SetText("")
+		compareRightEntry.SetText("")
+	}
+
+	compareButton := widget.NewButtonWithIcon("Compare", theme.SearchIcon(), func() {
+		left, err := compareSideTarget(leftConnection, leftDBI)
+		if err != nil {
+			showErrorLog("Left: " + err.Error())
+			return
+		}
+		right, err := compareSideTarget(rightConnection, rightDBI)
+		if err != nil {
+			showErrorLog("Right: " + err.Error())
+			return
+		}
+		startCompare(left, right, prefixEntry.Text)
+	})
+	prefixEntry.OnSubmitted = func(s string) {
+		compareButton.OnTapped()
+	}
+	stopButton := widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), func() {
+		if compareCancel != nil {
+			compareCancel()
+		}
+	})
+
+	filters := container.NewHBox(widget.NewLabel("Show:"))
+	for _, kind := range []compare.Kind{compare.OnlyLeft, compare.OnlyRight, compare.Changed} {
+		kind := kind
+		check := widget.NewCheck(kind.String(), func(b bool) {
+			compareLock.Lock()
+			compareShowKinds[kind] = b
+			compareLock.Unlock()
+			filterCompareResults()
+		})
+		check.SetChecked(true)
+		filters.Add(check)
+	}
+	selectAllButton := widget.NewButton("Select All", func() {
+		compareLock.Lock()
+		for _, index := range compareVisible {
+			compareChecked[index] = true
+		}
+		compareLock.Unlock()
+		compareList.Refresh()
+	})
+	selectNoneButton := widget.NewButton("Select None", func() {
+		compareChecked = make(map[int]bool)
+		compareList.Refresh()
+	})
+	copyRightButton := widget.NewButtonWithIcon("Copy to Right", theme.NavigateNextIcon(), func() {
+		copyCompareDifferences(w, true)
+	})
+	copyLeftButton := widget.NewButtonWithIcon("Copy to Left", theme.NavigateBackIcon(), func() {
+		copyCompareDifferences(w, false)
+	})
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		stopCompare()
+		showKeyValesTabItem()
+	})
+
+	sideForm := widget.NewForm(
+		widget.NewFormItem("Left", container.NewGridWithColumns(2, leftConnection, leftDBI)),
+		widget.NewFormItem("Right", container.NewGridWithColumns(2, rightConnection, rightDBI)),
+		widget.NewFormItem("Key Prefix", container.NewBorder(nil, nil, nil, container.NewHBox(compareButton, stopButton), prefixEntry)),
+	)
+	top := container.NewVBox(sideForm, container.NewBorder(nil, nil, filters, container.NewHBox(selectAllButton, selectNoneButton)), compareSummaryLabel)
+
+	leftLabel := widget.NewLabel("Left")
+	leftLabel.TextStyle = fyne.TextStyle{Bold: true}
+	rightLabel := widget.NewLabel("Right")
+	rightLabel.TextStyle = fyne.TextStyle{Bold: true}
+	values := container.NewGridWithColumns(2,
+		container.NewBorder(leftLabel, nil, nil, nil, compareLeftEntry),
+		container.NewBorder(rightLabel, nil, nil, nil, compareRightEntry))
+	split := container.NewHSplit(compareList, values)
+	split.Offset = 0.35
+
+	border := container.NewBorder(top, container.NewGridWithColumns(3, copyRightButton, copyLeftButton, backButton), nil, nil, split)
+	border.Hide()
+	return border
+}
+
+// newCompareSideSelects 创建选择连接和数据库的下拉框，选择连接后列出其中的命名数据库
+func newCompareSideSelects() (*widget.Select, *widget.Select) {
+	dbiSelect := widget.NewSelect(nil, nil)
+	dbiSelect.PlaceHolder = "Database"
+	connectionSelect := widget.NewSelect(nil, func(s string) {
+		index := compareConnectionIndex(s)
+		if index < 0 {
+			return
+		}
+		options := []string{dbiDisplayName(store.RootDBIName)}
+		compareEnv, err := openCompareEnv(index)
+		if err != nil {
+			showErrorLog("Error opening LMDB database: " + err.Error())
+		} else if names, err := store.ListDBINames(compareEnv, config.Config.Connections[index].MaxDBs); err != nil {
+			showErrorLog("Error listing LMDB databases: " + err.Error())
+		} else {
+			options = append(options, names...)
+		}
+		dbiSelect.Options = options
+		dbiSelect.SetSelected(options[0])
+	})
+	connectionSelect.PlaceHolder = "Connection"
+	compareConnectionSelects = append(compareConnectionSelects, connectionSelect)
+	return connectionSelect, dbiSelect
+}
+
+func showCompareTabItem() {
+	err := tabTitle.Set("Compare")
+	if err != nil {
+		return
+	}
+	// 连接可能已经增删，重新生成连接选项
+	options := make([]string, len(config.Config.Connections))
+	for i, connection := range config.Config.Connections {
+		options[i] = compareConnectionOption(i, connection.Name)
+	}
+	for _, connectionSelect := range compareConnectionSelects {
+		connectionSelect.Options = options
+		if compareConnectionIndex(connectionSelect.Selected) < 0 {
+			connectionSelect.ClearSelected()
+		}
+		connectionSelect.Refresh()
+	}
+	compareTabItem.Show()
+	transformTabItem.Hide()
+	historyTabItem.Hide()
+	pendingTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+// compareConnectionOption 生成连接下拉框的选项，加上序号以区分同名的连接
+func compareConnectionOption(index int, name string) string {
+	return strconv.Itoa(index+1) + ". " + name
+}
+
+// compareConnectionIndex 返回下拉框选项对应的连接下标，不存在时返回 -1
+func compareConnectionIndex(option string) int {
+	for i, connection := range config.Config.Connections {
+		if compareConnectionOption(i, connection.Name) == option {
+			return i
+		}
+	}
+	return -1
+}
+
+func compareSideTarget(connectionSelect, dbiSelect *widget.Select) (compareTarget, error) {
+	index := compareConnectionIndex(connectionSelect.Selected)
+	if index < 0 {
+		return compareTarget{}, errors.New("no connection selected")
+	}
+	if dbiSelect.Selected == "" {
+		return compareTarget{}, errors.New("no database selected")
+	}
+	target := compareTarget{connection: index, dbiName: dbiSelect.Selected}
+	if dbiSelect.Selected == dbiDisplayName(store.RootDBIName) {
+		target.dbiName = store.RootDBIName
+	}
+	return target, nil
+}
+
+// openCompareEnv 返回连接的环境。已在标签页中打开的连接直接使用其环境，其他连接按需打开并缓存
+func openCompareEnv(index int) (*lmdb.Env, error) {
+	if sideEnv := sessionEnv(index); sideEnv != nil {
+		return sideEnv, nil
+	}
+	if compareEnv, ok := compareEnvs[index]; ok {
+		return compareEnv, nil
+	}
+	compareEnv, err := store.OpenEnv(config.Config.Connections[index])
+	if err != nil {
+		return nil, err
+	}
+	compareEnvs[index] = compareEnv
+	return compareEnv, nil
+}
+
+// releaseCompareEnv 在打开或关闭连接 index 的环境之前调用：关闭缓存的同一个环境，避免同一个环境被打开两次；
+// 正在进行的比较使用了该连接时先停止比较
+func releaseCompareEnv(index int) {
+	if compareRunning() {
+		for _, side := range compareSides {
+			if side.connection == index {
+				stopCompare()
+				compareSummaryLabel.SetText("Comparison stopped because a connection was reopened")
+				break
+			}
+		}
+	}
+	if compareEnv, ok := compareEnvs[index]; ok {
+		compareEnv.Close()
+		delete(compareEnvs, index)
+	}
+}
+
+// resetCompare 停止比较、关闭所有缓存的环境并清空结果，连接下标变化后调用
+func resetCompare() {
+	stopCompare()
+	for index, compareEnv := range compareEnvs {
+		compareEnv.Close()
+		delete(compareEnvs, index)
+	}
+	setCompareResults(nil)
+	compareChecked = make(map[int]bool)
+	filterCompareResults()
+	compareSummaryLabel.SetText("Select two databases and click Compare")
+}
+
+// openCompareSide 打开比较一侧的环境和数据库
+func openCompareSide(target compareTarget) (compare.Side, error) {
+	sideEnv, err := openCompareEnv(target.connection)
+	if err != nil {
+		return compare.Side{}, err
+	}
+	sideDBI, err := store.OpenDBI(sideEnv, target.dbiName)
+	if err != nil {
+		return compare.Side{}, err
+	}
+	return compare.Side{Env: sideEnv, DBI: sideDBI}, nil
+}
+
+// compareRunning 表示比较协程仍在运行
+func compareRunning() bool {
+	if compareDone == nil {
+		return false
+	}
+	select {
+	case <-compareDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// stopCompare 停止正在进行的比较，并等待比较协程结束读事务
+func stopCompare() {
+	compareGeneration.Add(1)
+	if compareCancel != nil {
+		compareCancel()
+		compareCancel = nil
+	}
+	if compareDone != nil {
+		<-compareDone
+		compareDone = nil
+	}
+}
+
+// startCompare 在后台比较两个数据库，差异会陆续显示在列表中
+func startCompare(left, right compareTarget, prefixText string) {
+	stopCompare()
+	leftSide, err := openCompareSide(left)
+	if err != nil {
+		showErrorLog("Error opening left database: " + err.Error())
+		return
+	}
+	rightSide, err := openCompareSide(right)
+	if err != nil {
+		showErrorLog("Error opening right database: " + err.Error())
+		return
+	}
+	compareKeyCodec, err = codec.ParseKeyCodec(config.Config.Connections[left.connection].KeyLayout)
+	if err != nil {
+		compareKeyCodec, _ = codec.ParseKeyCodec(codec.DefaultKeyLayout)
+	}
+	prefix, err := compareKeyCodec.ParsePrefix(prefixText)
+	if err != nil {
+		showErrorLog("Invalid key prefix: " + err.Error())
+		return
+	}
+
+	generation := compareGeneration.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	compareCancel, compareDone = cancel, done
+	compareSides = [2]compareTarget{left, right}
+	setCompareResults(nil)
+	compareChecked = make(map[int]bool)
+	filterCompareResults()
+	compareSummaryLabel.SetText("Comparing...")
+
+	go func() {
+		lastRefresh := time.Now()
+		// 差异先收集在协程内，定期替换显示的结果
+		results := make([]compare.Difference, 0)
+		summary, err := compare.Compare(ctx, leftSide, rightSide, store.KeyRange{Prefix: prefix}, func(diff compare.Difference) bool {
+			if compareGeneration.Load() != generation {
+				return false
+			}
+			results = append(results, diff)
+			if time.Since(lastRefresh) > searchRefreshInterval {
+				lastRefresh = time.Now()
+				if !publishCompareResults(generation, results) {
+					return false
+				}
+				compareSummaryLabel.SetText(fmt.Sprintf("Comparing... %d differences", len(results)))
+				filterCompareResults()
+			}
+			return len(results) < maxCompareResults
+		})
+		close(done)
+		if !publishCompareResults(generation, results) {
+			return
+		}
+		cancel()
+
+		filterCompareResults()
+		text := fmt.Sprintf("%d keys compared: %d same, %d only left, %d only right, %d changed",
+			summary.Compared, summary.Same, summary.OnlyLeft, summary.OnlyRight, summary.Changed)
+		switch {
+		case errors.Is(err, context.Canceled):
+			text += " (stopped)"
+		case err != nil:
+			showErrorLog("Error comparing: " + err.Error())
+		case len(results) >= maxCompareResults:
+			text += fmt.Sprintf(" (stopped at %d differences)", maxCompareResults)
+		}
+		compareSummaryLabel.SetText(text)
+	}()
+}
+
+// publishCompareResults 替换比较结果，比较已停止或被新的比较取代时返回 false。
+// 在持有 compareLock 时检查编号，避免在清空结果之后写入旧的结果
+func publishCompareResults(generation int64, results []compare.Difference) bool {
+	compareLock.Lock()
+	defer compareLock.Unlock()
+	if compareGeneration.Load() != generation {
+		return false
+	}
+	// 协程之后继续追加差异，这里只使用当前长度的部分
+	compareResults = results[:len(results):len(results)]
+	return true
+}
+
+// setCompareResults 替换比较结果，调用者随后需要调用 filterCompareResults
+func setCompareResults(results []compare.Difference) {
+	compareLock.Lock()
+	defer compareLock.Unlock()
+	compareResults = results
+}
+
+// visibleCompareResult 返回列表中第 id 行的差异及其在 compareResults 中的下标
+func visibleCompareResult(id widget.ListItemID) (int, compare.Difference, bool) {
+	compareLock.Lock()
+	defer compareLock.Unlock()
+	if id < 0 || id >= len(compareVisible) {
+		return 0, compare.Difference{}, false
+	}
+	index := compareVisible[id]
+	return index, compareResults[index], true
+}
+
+// filterCompareResults 按选中的类型更新显示的差异
+func filterCompareResults() {
+	compareLock.Lock()
+	compareVisible = make([]int, 0, len(compareResults))
+	for i, diff := range compareResults {
+		if compareShowKinds[diff.Kind] {
+			compareVisible = append(compareVisible, i)
+		}
+	}
+	compareLock.Unlock()
+	compareList.UnselectAll()
+	compareList.Refresh()
+}
+
+// copyCompareDifferences 将勾选的差异从一侧复制到另一侧，在目标数据库的一个写事务中提交
+func copyCompareDifferences(w fyne.Window, toRight bool) {
+	if compareRunning() {
+		showErrorLog("Wait for the comparison to finish")
+		return
+	}
+	diffs := make([]compare.Difference, 0)
+	copied := make(map[int]bool)
+	compareLock.Lock()
+	for index, checked := range compareChecked {
+		if checked {
+			diffs = append(diffs, compareResults[index])
+			copied[index] = true
+		}
+	}
+	compareLock.Unlock()
+	if len(diffs) == 0 {
+		showErrorLog("No differences selected")
+		return
+	}
+	target, direction := compareSides[0], "right to left"
+	if toRight {
+		target, direction = compareSides[1], "left to right"
+	}
+	connection := config.Config.Connections[target.connection]
+	if connection.ReadOnly {
+		showErrorLog("Connection " + connection.Name + " is read-only")
+		return
+	}
+	message := fmt.Sprintf("Copy %d keys from %s into %s / %s?\nKeys missing on the source side will be deleted.",
+		len(diffs), direction, connection.Name, dbiDisplayName(target.dbiName))
+	dialog.ShowConfirm("Copy Differences", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		side, err := openCompareSide(target)
+		if err != nil {
+			showErrorLog("Error opening database: " + err.Error())
+			return
+		}
+		applied, err := compare.Copy(side, diffs, toRight)
+		if err != nil {
+			showErrorLog("Error copying differences, nothing was written: " + err.Error())
+			return
+		}
+		recordConnectionHistory(target.connection, target.dbiName, applied)
+
+		// 已复制的键两侧相同，从结果中移除
+		compareLock.Lock()
+		results := make([]compare.Difference, 0, len(compareResults)-len(diffs))
+		for i, diff := range compareResults {
+			if !copied[i] {
+				results = append(results, diff)
+			}
+		}
+		compareResults = results
+		compareLock.Unlock()
+		compareChecked = make(map[int]bool)
+		filterCompareResults()
+		showInfoLog(fmt.Sprintf("Copied %d keys from %s", len(diffs), direction))
+		if target.connection == selectedConnectionIndex && target.dbiName == selectedDBIName {
+			totalRecordsCached = false
+			reloadKeyValues()
+		}
+	}, w)
+}
@@ -0,0 +1,206 @@
+// Package compare 按键的顺序比较两个数据库，列出只在一侧存在和值不同的键
+package compare
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// checkInterval 为两次检查 ctx 是否取消之间比较的键数
+const checkInterval = 1000
+
+var errStop = errors.New("stop compare")
+
+// Kind 为差异的类型
+type Kind int
+
+const (
+	OnlyLeft Kind = iota
+	OnlyRight
+	Changed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case OnlyLeft:
+		return "Only Left"
+	case OnlyRight:
+		return "Only Right"
+	case Changed:
+		return "Changed"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Side 为参与比较的一个数据库
+type Side struct {
+	Env *lmdb.Env
+	DBI lmdb.DBI
+}
+
+// Difference 为一个键的差异，Left 和 Right 为两侧键的所有值（DupSort 数据库中为所有重复值），不存在时为空
+type Difference struct {
+	Kind  Kind
+	Key   []byte
+	Left  [][]byte
+	Right [][]byte
+}
+
+// Summary 为比较的统计
+type Summary struct {
+	Compared  int // 比较的键数（两侧合并后）
+	Same      int
+	OnlyLeft  int
+	OnlyRight int
+	Changed   int
+}
+
+// Compare 在两侧各一个读事务中按键的顺序同时遍历 keyRange 内的键，对每个差异调用 onDiff。
+// onDiff 返回 false 时停止比较；ctx 被取消时返回 ctx.Err()。keyRange 的方向被忽略，总是按升序比较
+func Compare(ctx context.Context, left, right Side, keyRange store.KeyRange, onDiff func(Difference) bool) (Summary, error) {
+	keyRange.Descending = false
+	var summary Summary
+	err := left.Env.View(func(leftTxn *lmdb.Txn) error {
+		return right.Env.View(func(rightTxn *lmdb.Txn) error {
+			leftCursor, err := newSideCursor(leftTxn, left.DBI, keyRange)
+			if err != nil {
+				return err
+			}
+			defer leftCursor.Close()
+			rightCursor, err := newSideCursor(rightTxn, right.DBI, keyRange)
+			if err != nil {
+				return err
+			}
+			defer rightCursor.Close()
+
+			if err := leftCursor.next(); err != nil {
+				return err
+			}
+			if err := rightCursor.next(); err != nil {
+				return err
+			}
+			for leftCursor.ok || rightCursor.ok {
+				summary.Compared++
+				if summary.Compared%checkInterval == 0 {
+					if err := ctx.Err(); err != nil {
+						return err
+					}
+				}
+
+				var cmp int
+				switch {
+				case !rightCursor.ok:
+					cmp = -1
+				case !leftCursor.ok:
+					cmp = 1
+				default:
+					cmp = bytes.Compare(leftCursor.key, rightCursor.key)
+				}
+
+				var diff Difference
+				var err error
+				switch {
+				case cmp < 0:
+					diff.Kind, diff.Key = OnlyLeft, append([]byte(nil), leftCursor.key...)
+					if diff.Left, err = leftCursor.values(); err != nil {
+						return err
+					}
+					summary.OnlyLeft++
+					err = leftCursor.next()
+				case cmp > 0:
+					diff.Kind, diff.Key = OnlyRight, append([]byte(nil), rightCursor.key...)
+					if diff.Right, err = rightCursor.values(); err != nil {
+						return err
+					}
+					summary.OnlyRight++
+					err = rightCursor.next()
+				default:
+					diff.Kind, diff.Key = Changed, append([]byte(nil), leftCursor.key...)
+					if diff.Left, err = leftCursor.values(); err != nil {
+						return err
+					}
+					if diff.Right, err = rightCursor.values(); err != nil {
+						return err
+					}
+					if store.EqualValues(diff.Left, diff.Right) {
+						summary.Same++
+						diff.Key = nil
+					} else {
+						summary.Changed++
+					}
+					if err = leftCursor.next(); err == nil {
+						err = rightCursor.next()
+					}
+				}
+				if err != nil {
+					return err
+				}
+				if diff.Key != nil && !onDiff(diff) {
+					return errStop
+				}
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+	return summary, err
+}
+
+// Copy 在 to 的一个写事务中将 diffs 中每个键的值设置为另一侧的值，fromLeft 为 true 时从左侧复制到右侧。
+// 另一侧不存在的键会被删除；目标一侧的键在比较之后被修改过时返回 *store.ConflictError，不做任何修改。
+// 返回已应用的修改
+func Copy(to Side, diffs []Difference, fromLeft bool) ([]store.Change, error) {
+	changes := make([]store.Change, 0, len(diffs))
+	for _, diff := range diffs {
+		source, target := diff.Right, diff.Left
+		if fromLeft {
+			source, target = diff.Left, diff.Right
+		}
+		changes = append(changes, store.SetValues(diff.Key, target, source)...)
+	}
+	return store.ApplyChanges(to.Env, to.DBI, changes)
+}
+
+// sideCursor 遍历一侧的键，DupSort 数据库中每个键只返回一次
+type sideCursor struct {
+	*store.KeyCursor
+	txn     *lmdb.Txn
+	dbi     lmdb.DBI
+	dupSort bool
+	key     []byte
+	val     []byte
+	ok      bool
+}
+
+func newSideCursor(txn *lmdb.Txn, dbi lmdb.DBI, keyRange store.KeyRange) (*sideCursor, error) {
+	flags, err := txn.Flags(dbi)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := store.NewKeyCursor(txn, dbi, keyRange, true)
+	if err != nil {
+		return nil, err
+	}
+	return &sideCursor{KeyCursor: cursor, txn: txn, dbi: dbi, dupSort: flags&lmdb.DupSort != 0}, nil
+}
+
+func (c *sideCursor) next() (err error) {
+	c.key, c.val, c.ok, err = c.Next()
+	return err
+}
+
+// values 返回当前键的所有值
+func (c *sideCursor) values() ([][]byte, error) {
+	if !c.dupSort {
+		return [][]byte{append([]byte(nil), c.val...)}, nil
+	}
+	return store.ReadValues(c.txn, c.dbi, c.key)
+}
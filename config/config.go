@@ -11,10 +11,26 @@ type ConnectionConfig struct {
 	Name         string `yaml:"name"`
 	DatabasePath string `yaml:"database_path"`
 	MapSize      int64  `yaml:"map_size"` // GB
+	MaxDBs       int    `yaml:"max_dbs"`
+	ReadOnly     bool   `yaml:"read_only"`
+	KeyLayout    string `yaml:"key_layout,omitempty"` // 键编码布局，例如 u64be、uuid、u32be|utf8，为空时按 UTF-8 处理
+
+	// Protobuf 解码：.proto 文件、导入路径、FileDescriptorSet 文件以及键前缀到消息类型的映射
+	ProtoFiles       []string       `yaml:"proto_files,omitempty"`
+	ProtoImportPaths []string       `yaml:"proto_import_paths,omitempty"`
+	DescriptorSets   []string       `yaml:"descriptor_sets,omitempty"`
+	ProtoMappings    []ProtoMapping `yaml:"proto_mappings,omitempty"`
+}
+
+// ProtoMapping 将以 KeyPrefix 开头的键的值按 Message 类型解码
+type ProtoMapping struct {
+	KeyPrefix string `yaml:"key_prefix"`
+	Message   string `yaml:"message"` // 消息全名，例如 example.User
 }
 
 type AppConfig struct {
-	Connections []ConnectionConfig `yaml:"connections"`
+	Connections     []ConnectionConfig `yaml:"connections"`
+	RefreshInterval int                `yaml:"refresh_interval,omitempty"` // 自动刷新检查数据库是否变化的间隔（秒），为 0 时使用 5 秒
 }
 
 var configPath = "lmdb-gui-client.yaml"
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// countGeneration 每次开始或停止计数时递增，旧的计数发现编号变化后不再更新界面
+var countGeneration atomic.Int64
+var countCancel context.CancelFunc
+
+// countDone 在计数协程结束读事务后关闭，关闭环境前需要等待
+var countDone chan struct{}
+var countCancelButton *widget.Button
+
+// initRecordCount 创建记录数标签和计数时显示的取消按钮
+func initRecordCount() *fyne.Container {
+	recordCountLabel = widget.NewLabel("Records: 0")
+	recordCountLabel.Alignment = fyne.TextAlignCenter
+	countCancelButton = widget.NewButtonWithIcon("", theme.CancelIcon(), func() {
+		stopCount()
+		recordCountLabel.SetText("Records: ? (cancelled)")
+	})
+	countCancelButton.Hide()
+	return container.NewBorder(nil, nil, nil, countCancelButton, recordCountLabel)
+}
+
+// totalRecordsKnown 表示记录总数已经统计完成
+func totalRecordsKnown() bool {
+	return totalRecords >= 0
+}
+
+// setTotalRecords 设置记录总数并更新记录数和页码标签
+func setTotalRecords(count int) {
+	totalRecords = count
+	recordCountLabel.SetText("Records: " + strconv.Itoa(totalRecords))
+	updatePageLabel()
+}
+
+// updatePageLabel 按记录总数计算总页数并更新页码标签，总数未知时显示 "?"
+func updatePageLabel() {
+	if !totalRecordsKnown() {
+		pageLabel.SetText(fmt.Sprintf("Page %d / ?", currentPage))
+		return
+	}
+	totalPage = int(math.Ceil(float64(totalRecords) / float64(pageSize)))
+	pageLabel.SetText(fmt.Sprintf("Page %d / %d", currentPage, totalPage))
+}
+
+// stopCount 停止正在进行的计数，并等待计数协程结束读事务
+func stopCount() {
+	countGeneration.Add(1)
+	if countCancel != nil {
+		countCancel()
+		countCancel = nil
+	}
+	if countDone != nil {
+		<-countDone
+		countDone = nil
+	}
+	countCancelButton.Hide()
+}
+
+// startCount 在后台统计当前范围内的记录数，统计期间表格仍可翻页，总页数显示为 "?"
+func startCount(keyRange store.KeyRange) {
+	stopCount()
+	generation := countGeneration.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	countCancel, countDone = cancel, done
+
+	totalRecords = -1
+	recordCountLabel.SetText("Records: counting…")
+	countCancelButton.Show()
+	updatePageLabel()
+
+	countEnv, countDBI, uniqueKeys := env, dbi, dbiIsDupSort
+	go func() {
+		count, err := store.CountKeys(ctx, countEnv, countDBI, keyRange, uniqueKeys)
+		close(done)
+		// 在界面事件队列中更新总数，与翻页时读取 totalRecords 不会同时进行
+		queueUIEvent(func() {
+			if countGeneration.Load() != generation {
+				return
+			}
+			cancel()
+			countCancelButton.Hide()
+			switch {
+			case errors.Is(err, context.Canceled):
+			case err != nil:
+				recordCountLabel.SetText("Records: ?")
+				showErrorLog("Error counting records: " + err.Error())
+			default:
+				setTotalRecords(count)
+			}
+		})
+	}()
+}
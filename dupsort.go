@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 每页加载的重复值数量
+const dupPageSize = 100
+
+var dbiIsDupSort bool
+var dupValues [][]byte
+var dupTotal uint64
+var selectedDupIndex = -1
+var dupValuesList *widget.List
+var dupCountLabel *widget.Label
+
+func initDupValuesPanel(valueView *widget.Entry) *fyne.Container {
+	dupCountLabel = widget.NewLabel("Duplicates: 0")
+	dupCountLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	dupValuesList = widget.NewList(
+		func() int { return len(dupValues) },
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(truncateToFit(codec.DisplayString(dupValues[i]), 600, oneCharWidth))
+		},
+	)
+	dupValuesList.OnSelected = func(id widget.ListItemID) {
+		if id < 0 || id >= len(dupValues) {
+			return
+		}
+		selectedDupIndex = id
+		setValueViewBytes(dupValues[id])
+	}
+	dupValuesList.OnUnselected = func(id widget.ListItemID) {
+		selectedDupIndex = -1
+		clearValueView()
+	}
+
+	prevButton := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() {
+		if selectedKey != "" && len(dupValues) > 0 {
+			loadDupValues(selectedKey, dupValues[0], true)
+		}
+	})
+	nextButton := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() {
+		if selectedKey != "" && len(dupValues) > 0 {
+			loadDupValues(selectedKey, dupValues[len(dupValues)-1], false)
+		}
+	})
+
+	addButton := widget.NewButtonWithIcon("Add Dup", theme.ContentAddIcon(), func() {
+		if selectedKey != "" {
+			value, err := valueViewBytes()
+			if err != nil {
+				showErrorLog("Invalid value: " + err.Error())
+				return
+			}
+			insertOrUpdateKeyValue(selectedKey, string(value))
+			loadDupValues(selectedKey, nil, false)
+		}
+	})
+	deleteAllButton := widget.NewButtonWithIcon("Delete All", theme.DeleteIcon(), func() {
+		if selectedKey != "" {
+			deleteKeyValue(selectedKey)
+			keyValueTable.UnselectAll()
+		}
+	})
+
+	header := container.NewBorder(nil, nil, dupCountLabel, container.NewHBox(prevButton, nextButton))
+	writeButtons = append(writeButtons, addButton, deleteAllButton)
+	controls := container.NewGridWithColumns(2, addButton, deleteAllButton)
+	return container.NewBorder(header, controls, nil, nil, dupValuesList)
+}
+
+// loadDupValues 读取 key 的一页重复值。from 为空时从第一个重复值开始，
+// 否则从 from 之后（backward 为 true 时为之前）的重复值开始读取
+func loadDupValues(key string, from []byte, backward bool) {
+	values := make([][]byte, 0, dupPageSize)
+	err := env.View(func(txn *lmdb.Txn) error {
+		cur, err := txn.OpenCursor(dbi)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		_, v, err := cur.Get([]byte(key), nil, lmdb.Set)
+		if err != nil {
+			return err
+		}
+		dupTotal, err = cur.Count()
+		if err != nil {
+			return err
+		}
+
+		op := uint(lmdb.NextDup)
+		if backward {
+			op = lmdb.PrevDup
+		}
+		if from != nil {
+			if _, _, err = cur.Get([]byte(key), from, lmdb.GetBoth); err != nil {
+				return err
+			}
+			_, v, err = cur.Get(nil, nil, op)
+		}
+		for err == nil && len(values) < dupPageSize {
+			values = append(values, append([]byte(nil), v...))
+			_, v, err = cur.Get(nil, nil, op)
+		}
+		if err != nil && !lmdb.IsNotFound(err) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		showErrorLog("Error loading duplicate values: " + err.Error())
+		return
+	}
+	if len(values) == 0 && from != nil {
+		// 已经到达第一页或最后一页
+		return
+	}
+	if backward {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	dupValues = values
+	selectedDupIndex = -1
+	dupValuesList.UnselectAll()
+	dupValuesList.Refresh()
+	dupCountLabel.SetText(fmt.Sprintf("Duplicates: %d", dupTotal))
+	if len(dupValues) > 0 {
+		dupValuesList.Select(0)
+	}
+}
+
+// replaceDupValue 在同一个事务中删除旧的重复值并写入新值
+func replaceDupValue(key string, oldValue []byte, newValue string) {
+	writeChange(store.Change{Kind: store.ChangeReplaceDup, Key: []byte(key), Dup: oldValue, Value: []byte(newValue)},
+		"Duplicate value updated", "Error updating duplicate value: ")
+}
+
+// deleteDupValue 删除 key 下的单个重复值
+func deleteDupValue(key string, value []byte) {
+	writeChange(store.Change{Kind: store.ChangeDeleteDup, Key: []byte(key), Dup: value},
+		"Duplicate value deleted", "Error deleting duplicate value: ")
+}
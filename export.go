@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+	"github.com/zshimonz/lmdb-gui-client/transfer"
+)
+
+// showExportDialog 选择导出格式、编码和范围后，将当前数据库导出到文件
+func showExportDialog(w fyne.Window) {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	prefixText, err := keyPrefix.Get()
+	if err != nil {
+		return
+	}
+	keyRange, err := currentKeyRange(prefixText)
+	if err != nil {
+		showErrorLog(err.Error())
+		return
+	}
+
+	encodingSelect := widget.NewSelect(transfer.Encodings(), nil)
+	encodingSelect.SetSelected(string(transfer.EncodingBase64))
+	formatSelect := widget.NewSelect(transfer.Formats(), func(s string) {
+		// mdb_dump 固定使用十六进制
+		if transfer.Format(s) == transfer.FormatMDBDump {
+			encodingSelect.Disable()
+		} else {
+			encodingSelect.Enable()
+		}
+	})
+	formatSelect.SetSelected(string(transfer.FormatJSONL))
+	rangeCheck := widget.NewCheck("Only keys in the current prefix and range filter", nil)
+	if keyRange.IsEmpty() {
+		rangeCheck.Disable()
+	} else {
+		rangeCheck.SetChecked(true)
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Database", widget.NewLabel(config.Config.Connections[selectedConnectionIndex].Name+" / "+dbiDisplayName(selectedDBIName))),
+		widget.NewFormItem("Format", formatSelect),
+		widget.NewFormItem("Encoding", encodingSelect),
+		widget.NewFormItem("Key Range", rangeCheck),
+	}
+	dialog.ShowForm("Export", "Export", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		// 不限制范围时仍按当前的遍历方向导出
+		if !rangeCheck.Checked {
+			keyRange = store.KeyRange{Descending: keyRange.Descending}
+		}
+		format := transfer.Format(formatSelect.Selected)
+		encoding := transfer.Encoding(encodingSelect.Selected)
+		fd := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				showErrorLog("Error opening export file: " + err.Error())
+				return
+			}
+			if writer == nil {
+				return
+			}
+			exportKeyValues(w, writer, format, encoding, keyRange)
+		}, w)
+		fd.SetFileName(config.Config.Connections[selectedConnectionIndex].Name + format.FileExtension())
+		fd.Resize(fyne.NewSize(windowWidth, windowHeight))
+		fd.Show()
+	}, w)
+}
+
+// exportKeyValues 在后台导出，并在对话框中显示进度，点击 Cancel 可中止导出
+func exportKeyValues(w fyne.Window, writer fyne.URIWriteCloser, format transfer.Format, encoding transfer.Encoding, keyRange store.KeyRange) {
+	exportEnv, exportDBI, exportDBIName := env, dbi, selectedDBIName
+
+	progressBar := widget.NewProgressBar()
+	progressLabel := widget.NewLabel("Counting records...")
+	ctx, cancel := context.WithCancel(context.Background())
+	progressDialog := dialog.NewCustom("Exporting", "Cancel", widget.NewForm(
+		widget.NewFormItem("File", widget.NewLabel(writer.URI().Name())),
+		widget.NewFormItem("Progress", progressBar),
+		widget.NewFormItem("", progressLabel),
+	), w)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		defer progressDialog.Hide()
+		defer writer.Close()
+
+		total, err := store.CountKeys(ctx, exportEnv, exportDBI, keyRange, false)
+		if errors.Is(err, context.Canceled) {
+			showInfoLog("Export cancelled")
+			return
+		}
+		if err != nil {
+			showErrorLog("Error counting records: " + err.Error())
+			return
+		}
+		if total > 0 {
+			progressBar.Max = float64(total)
+		}
+
+		header, err := transfer.NewDumpHeader(exportEnv, exportDBI, exportDBIName)
+		if err != nil {
+			showErrorLog("Error reading database info: " + err.Error())
+			return
+		}
+		out, err := transfer.NewWriter(writer, format, encoding, header)
+		if err != nil {
+			showErrorLog("Error writing export file: " + err.Error())
+			return
+		}
+		count, err := transfer.Export(ctx, exportEnv, exportDBI, keyRange, out, func(done int) {
+			progressBar.SetValue(float64(done))
+			progressLabel.SetText(fmt.Sprintf("%d / %d records", done, total))
+		})
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if errors.Is(err, context.Canceled) {
+			showInfoLog(fmt.Sprintf("Export cancelled after %d records", count))
+			return
+		}
+		if err != nil {
+			showErrorLog("Error exporting: " + err.Error())
+			return
+		}
+		showInfoLog(fmt.Sprintf("Exported %d records to %s", count, writer.URI().Path()))
+	}()
+}
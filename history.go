@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/history"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 历史记录列表中每条记录最多列出的键数量
+const maxHistorySummaryKeys = 3
+
+// writeHistory 为当前连接的写操作历史，未连接时为 nil
+var writeHistory *history.History
+
+var historyTabItem *fyne.Container
+var historyList *widget.List
+var historyDetailEntry *widget.Entry
+
+// loadHistory 读取连接的写操作历史
+func loadHistory(connection config.ConnectionConfig) {
+	var err error
+	writeHistory, err = history.Open(connection.DatabasePath)
+	if err != nil {
+		showErrorLog("Error loading history: " + err.Error())
+	}
+	if historyTabItem.Visible() {
+		refreshHistory()
+	}
+}
+
+// recordHistory 将已提交的修改加入当前连接的历史
+func recordHistory(changes []store.Change) {
+	if writeHistory == nil {
+		return
+	}
+	if err := writeHistory.Record(selectedDBIName, changes); err != nil {
+		showErrorLog("Error saving history: " + err.Error())
+	}
+	if historyTabItem.Visible() {
+		refreshHistory()
+	}
+}
+
+// recordConnectionHistory 将已提交的修改加入连接的历史，用于写入的不是当前标签页的数据库或在后台提交的修改。
+// 连接已在标签页中打开时使用已加载的历史
+func recordConnectionHistory(connectionIndex int, dbiName string, changes []store.Change) {
+	if findSession(connectionIndex) != nil {
+		if h := sessionHistory(connectionIndex); h != nil {
+			if err := h.Record(dbiName, changes); err != nil {
+				showErrorLog("Error saving history: " + err.Error())
+			}
+			if historyTabItem.Visible() {
+				refreshHistory()
+			}
+		}
+		return
+	}
+	// 其他连接的历史在下次连接时重新读取
+	h, err := history.Open(config.Config.Connections[connectionIndex].DatabasePath)
+	if err == nil {
+		err = h.Record(dbiName, changes)
+	}
+	if err != nil {
+		showErrorLog("Error saving history: " + err.Error())
+	}
+}
+
+// initHistoryShortcuts 注册撤销（Ctrl+Z）和重做（Ctrl+Y、Ctrl+Shift+Z）快捷键
+func initHistoryShortcuts(w fyne.Window) {
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+		undoWrite()
+	})
+	redo := func(fyne.Shortcut) {
+		redoWrite()
+	}
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyY, Modifier: fyne.KeyModifierShortcutDefault}, redo)
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}, redo)
+}
+
+func initHistoryTabItem() *fyne.Container {
+	historyList = widget.NewList(
+		func() int {
+			if writeHistory == nil {
+				return 0
+			}
+			return len(writeHistory.Entries)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			// 最近的记录显示在最上面
+			index := len(writeHistory.Entries) - 1 - i
+			label := o.(*widget.Label)
+			label.SetText(historySummary(writeHistory.Entries[index]))
+			if index >= writeHistory.Position {
+				label.Importance = widget.LowImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+			label.Refresh()
+		},
+	)
+	historyDetailEntry = widget.NewMultiLineEntry()
+	historyDetailEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	historyDetailEntry.Disable()
+	historyList.OnSelected = func(id widget.ListItemID) {
+		if writeHistory == nil || id < 0 || id >= len(writeHistory.Entries) {
+			return
+		}
+		historyDetailEntry.SetText(historyDetail(writeHistory.Entries[len(writeHistory.Entries)-1-id]))
+	}
+	historyList.OnUnselected = func(id widget.ListItemID) {
+		historyDetailEntry.SetText("")
+	}
+
+	undoButton := widget.NewButtonWithIcon("Undo", theme.ContentUndoIcon(), func() {
+		undoWrite()
+	})
+	redoButton := widget.NewButtonWithIcon("Redo", theme.ContentRedoIcon(), func() {
+		redoWrite()
+	})
+	writeButtons = append(writeButtons, undoButton, redoButton)
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		showKeyValesTabItem()
+	})
+
+	hint := widget.NewLabel("Greyed entries have been undone and can be redone. Ctrl+Z undoes, Ctrl+Y redoes.")
+	split := container.NewHSplit(historyList, historyDetailEntry)
+	split.Offset = 0.45
+	border := container.NewBorder(hint, container.NewGridWithColumns(3, undoButton, redoButton, backButton), nil, nil, split)
+	border.Hide()
+	return border
+}
+
+func showHistoryTabItem() {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	err := tabTitle.Set("History")
+	if err != nil {
+		return
+	}
+	refreshHistory()
+	historyTabItem.Show()
+	pendingTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+func refreshHistory() {
+	historyList.UnselectAll()
+	historyList.Refresh()
+}
+
+// undoWrite 撤销当前连接最近一次写操作
+func undoWrite() {
+	if writeHistory == nil || selectedConnectionIndex == -1 {
+		return
+	}
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	if !writeHistory.CanUndo() {
+		showInfoLog("Nothing to undo")
+		return
+	}
+	entry, err := writeHistory.Undo(env)
+	if err != nil {
+		showErrorLog("Error undoing " + historySummary(entry) + ": " + err.Error())
+		return
+	}
+	showInfoLog("Undone: " + historySummary(entry))
+	afterHistoryChange()
+}
+
+// redoWrite 重做当前连接最近一次撤销的写操作
+func redoWrite() {
+	if writeHistory == nil || selectedConnectionIndex == -1 {
+		return
+	}
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	if !writeHistory.CanRedo() {
+		showInfoLog("Nothing to redo")
+		return
+	}
+	entry, err := writeHistory.Redo(env)
+	if err != nil {
+		showErrorLog("Error redoing " + historySummary(entry) + ": " + err.Error())
+		return
+	}
+	showInfoLog("Redone: " + historySummary(entry))
+	afterHistoryChange()
+}
+
+func afterHistoryChange() {
+	if historyTabItem.Visible() {
+		refreshHistory()
+	}
+	totalRecordsCached = false
+	reloadKeyValues()
+	keyValueTable.UnselectAll()
+}
+
+// historySummary 返回记录的单行摘要，例如 "15:04:05 users: Put alice, Delete bob"
+func historySummary(entry history.Entry) string {
+	keys := make([]string, 0, maxHistorySummaryKeys)
+	for i, change := range entry.Changes {
+		if i == maxHistorySummaryKeys {
+			keys = append(keys, fmt.Sprintf("+%d more", len(entry.Changes)-maxHistorySummaryKeys))
+			break
+		}
+		keys = append(keys, change.Kind.String()+" "+keyCodec.Format(change.Key))
+	}
+	return fmt.Sprintf("%s %s: %s", entry.Time.Format("2006-01-02 15:04:05"), dbiDisplayName(entry.Database), strings.Join(keys, ", "))
+}
+
+// historyDetail 列出记录中每个键修改前后的值
+func historyDetail(entry history.Entry) string {
+	var sb strings.Builder
+	for i, change := range entry.Changes {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "%s %s\n", change.Kind, keyCodec.Format(change.Key))
+		fmt.Fprintf(&sb, "Before:\n%s\n", formatDiffValues(change.Before))
+		fmt.Fprintf(&sb, "After:\n%s", formatDiffValues(change.After))
+	}
+	return sb.String()
+}
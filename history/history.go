@@ -0,0 +1,140 @@
+// Package history 记录客户端对每个连接所做的写操作，用于撤销和重做
+package history
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// Dir 为保存历史记录文件的目录
+var Dir = "lmdb-gui-client-history"
+
+// MaxEntries 为每个连接保留的最大记录数，超出时丢弃最早的记录
+const MaxEntries = 200
+
+// Entry 为一次提交的写操作，Changes 中记录了每个键修改前后的值
+type Entry struct {
+	Time     time.Time      `json:"time"`
+	Database string         `json:"database"` // 命名数据库名，根数据库为空
+	Changes  []store.Change `json:"changes"`
+}
+
+// History 为一个连接的写操作历史。Entries[:Position] 为已应用的记录，之后的为已撤销、可以重做的记录
+type History struct {
+	path     string
+	Entries  []Entry `json:"entries"`
+	Position int     `json:"position"`
+}
+
+// Open 读取数据库路径为 databasePath 的连接的历史记录，文件不存在时返回空的历史记录
+func Open(databasePath string) (*History, error) {
+	h := &History{path: historyPath(databasePath)}
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return h, err
+	}
+	if h.Position < 0 || h.Position > len(h.Entries) {
+		h.Position = len(h.Entries)
+	}
+	return h, nil
+}
+
+// historyPath 按数据库路径的绝对路径生成历史记录文件名，连接改名或调整顺序后仍使用同一个文件
+func historyPath(databasePath string) string {
+	if abs, err := filepath.Abs(databasePath); err == nil {
+		databasePath = abs
+	}
+	sum := sha1.Sum([]byte(databasePath))
+	return filepath.Join(Dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// CanUndo 表示有可以撤销的记录
+func (h *History) CanUndo() bool {
+	return h.Position > 0
+}
+
+// CanRedo 表示有可以重做的记录
+func (h *History) CanRedo() bool {
+	return h.Position < len(h.Entries)
+}
+
+// Record 记录一次已提交的写操作，并丢弃所有可以重做的记录
+func (h *History) Record(database string, changes []store.Change) error {
+	h.Entries = append(h.Entries[:h.Position], Entry{Time: time.Now(), Database: database, Changes: changes})
+	if len(h.Entries) > MaxEntries {
+		h.Entries = h.Entries[len(h.Entries)-MaxEntries:]
+	}
+	h.Position = len(h.Entries)
+	return h.save()
+}
+
+// Undo 在一个写事务中将最近一次记录中的键恢复为修改前的值。
+// 键在之后又被修改过时返回 *store.ConflictError，不做任何修改
+func (h *History) Undo(env *lmdb.Env) (Entry, error) {
+	if !h.CanUndo() {
+		return Entry{}, errors.New("nothing to undo")
+	}
+	entry := h.Entries[h.Position-1]
+	changes := make([]store.Change, 0, len(entry.Changes))
+	for i := len(entry.Changes) - 1; i >= 0; i-- {
+		change := entry.Changes[i]
+		changes = append(changes, store.SetValues(change.Key, change.After, change.Before)...)
+	}
+	if err := apply(env, entry.Database, changes); err != nil {
+		return entry, err
+	}
+	h.Position--
+	return entry, h.save()
+}
+
+// Redo 在一个写事务中重新应用最近一次撤销的记录
+func (h *History) Redo(env *lmdb.Env) (Entry, error) {
+	if !h.CanRedo() {
+		return Entry{}, errors.New("nothing to redo")
+	}
+	entry := h.Entries[h.Position]
+	changes := make([]store.Change, 0, len(entry.Changes))
+	for _, change := range entry.Changes {
+		changes = append(changes, store.SetValues(change.Key, change.Before, change.After)...)
+	}
+	if err := apply(env, entry.Database, changes); err != nil {
+		return entry, err
+	}
+	h.Position++
+	return entry, h.save()
+}
+
+func apply(env *lmdb.Env, database string, changes []store.Change) error {
+	dbi, err := store.OpenDBI(env, database)
+	if err != nil {
+		return err
+	}
+	_, err = store.ApplyChanges(env, dbi, changes)
+	return err
+}
+
+func (h *History) save() error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, data, 0644)
+}
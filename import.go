@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+	"github.com/zshimonz/lmdb-gui-client/transfer"
+)
+
+// importErrorsShown 为导入结果中显示的失败原因条数
+const importErrorsShown = 10
+
+// countingReader 记录已读取的字节数，用于显示导入进度
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// showImportDialog 导入向导：选择文件后设置格式、编码、CSV 列映射、冲突策略和批次大小
+func showImportDialog(w fyne.Window) {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	fd := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			showErrorLog("Error opening import file: " + err.Error())
+			return
+		}
+		if reader == nil {
+			return
+		}
+		showImportOptions(w, reader)
+	}, w)
+	fd.Resize(fyne.NewSize(windowWidth, windowHeight))
+	fd.Show()
+}
+
+func showImportOptions(w fyne.Window, reader fyne.URIReadCloser) {
+	encodingSelect := widget.NewSelect(transfer.ImportEncodings(), nil)
+	encodingSelect.SetSelected(string(transfer.EncodingBase64))
+	headerCheck := widget.NewCheck("First row is header", nil)
+	headerCheck.SetChecked(true)
+	keyColumnEntry := widget.NewEntry()
+	keyColumnEntry.SetText("key")
+	keyColumnEntry.SetPlaceHolder("Column name or number")
+	valueColumnEntry := widget.NewEntry()
+	valueColumnEntry.SetText("value")
+	valueColumnEntry.SetPlaceHolder("Column name or number")
+	csvWidgets := []fyne.Disableable{headerCheck, keyColumnEntry, valueColumnEntry}
+
+	formatSelect := widget.NewSelect(transfer.Formats(), func(s string) {
+		format := transfer.Format(s)
+		// mdb_dump 固定使用十六进制，列映射只对 CSV 有效
+		if format == transfer.FormatMDBDump {
+			encodingSelect.Disable()
+		} else {
+			encodingSelect.Enable()
+		}
+		for _, item := range csvWidgets {
+			if format == transfer.FormatCSV {
+				item.Enable()
+			} else {
+				item.Disable()
+			}
+		}
+	})
+	switch strings.ToLower(reader.URI().Extension()) {
+	case ".jsonl", ".json", ".ndjson":
+		formatSelect.SetSelected(string(transfer.FormatJSONL))
+	case ".csv":
+		formatSelect.SetSelected(string(transfer.FormatCSV))
+	default:
+		formatSelect.SetSelected(string(transfer.FormatMDBDump))
+	}
+	conflictSelect := widget.NewSelect(transfer.ConflictPolicies(), nil)
+	conflictSelect.SetSelected(string(transfer.ConflictSkip))
+	batchSizeEntry := widget.NewEntry()
+	batchSizeEntry.SetText("1000")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("File", widget.NewLabel(reader.URI().Name())),
+		widget.NewFormItem("Format", formatSelect),
+		widget.NewFormItem("Encoding", encodingSelect),
+		widget.NewFormItem("CSV Header", headerCheck),
+		widget.NewFormItem("Key Column", keyColumnEntry),
+		widget.NewFormItem("Value Column", valueColumnEntry),
+		widget.NewFormItem("Existing Keys", conflictSelect),
+		widget.NewFormItem("Batch Size", batchSizeEntry),
+	}
+	form := dialog.NewForm("Import", "Import", "Cancel", items, func(ok bool) {
+		if !ok {
+			reader.Close()
+			return
+		}
+		if !isPositiveInteger(batchSizeEntry.Text) {
+			showErrorLog("Batch size must be a positive integer")
+			reader.Close()
+			return
+		}
+		batchSize, _ := strconv.Atoi(batchSizeEntry.Text)
+		mapping := transfer.CSVMapping{
+			HasHeader:   headerCheck.Checked,
+			KeyColumn:   keyColumnEntry.Text,
+			ValueColumn: valueColumnEntry.Text,
+		}
+		importKeyValues(w, reader, transfer.Format(formatSelect.Selected), transfer.Encoding(encodingSelect.Selected),
+			mapping, transfer.ConflictPolicy(conflictSelect.Selected), batchSize)
+	}, w)
+	form.Resize(fyne.NewSize(windowWidth/2, 0))
+	form.Show()
+}
+
+// importKeyValues 在后台导入，并在对话框中显示进度，完成后显示导入结果。每个已提交的批次加入开始时所在连接的历史
+func importKeyValues(w fyne.Window, reader fyne.URIReadCloser, format transfer.Format, encoding transfer.Encoding,
+	mapping transfer.CSVMapping, policy transfer.ConflictPolicy, batchSize int) {
+	importEnv, importDBI := env, dbi
+	connectionIndex, dbiName := selectedConnectionIndex, selectedDBIName
+	var fileSize int64
+	if info, err := os.Stat(reader.URI().Path()); err == nil {
+		fileSize = info.Size()
+	}
+	counter := &countingReader{r: reader}
+
+	progressBar := widget.NewProgressBar()
+	progressLabel := widget.NewLabel("Reading...")
+	ctx, cancel := context.WithCancel(context.Background())
+	progressDialog := dialog.NewCustom("Importing", "Cancel", widget.NewForm(
+		widget.NewFormItem("File", widget.NewLabel(reader.URI().Name())),
+		widget.NewFormItem("Progress", progressBar),
+		widget.NewFormItem("", progressLabel),
+	), w)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		defer reader.Close()
+
+		records, err := transfer.NewReader(counter, format, encoding, mapping)
+		if err != nil {
+			progressDialog.Hide()
+			showErrorLog("Error reading import file: " + err.Error())
+			return
+		}
+		result, err := transfer.Import(ctx, importEnv, importDBI, records, policy, batchSize, func(result transfer.ImportResult, changes []store.Change) {
+			if len(changes) > 0 {
+				queueUIEvent(func() {
+					recordConnectionHistory(connectionIndex, dbiName, changes)
+				})
+			}
+			if fileSize > 0 {
+				progressBar.SetValue(float64(counter.n.Load()) / float64(fileSize))
+			}
+			progressLabel.SetText(fmt.Sprintf("Inserted %d, skipped %d, failed %d", result.Inserted, result.Skipped, result.Failed))
+		})
+		progressDialog.Hide()
+
+		summary := fmt.Sprintf("Inserted: %d\nSkipped: %d\nFailed: %d", result.Inserted, result.Skipped, result.Failed)
+		switch {
+		case errors.Is(err, context.Canceled):
+			summary = "Import cancelled.\n\n" + summary
+		case err != nil:
+			summary = "Import stopped, the current batch was rolled back: " + err.Error() + "\n\n" + summary
+		}
+		for i, message := range result.Errors {
+			if i == importErrorsShown {
+				summary += fmt.Sprintf("\n... and %d more", result.Failed-importErrorsShown)
+				break
+			}
+			summary += "\n" + message
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			showErrorLog("Error importing: " + err.Error())
+		} else {
+			showInfoLog(fmt.Sprintf("Imported %d records", result.Inserted))
+		}
+		dialog.ShowInformation("Import Result", summary, w)
+		queueReloadKeyValues(importEnv, importDBI)
+	}()
+}
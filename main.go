@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,28 +21,42 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/PowerDNS/lmdb-go/lmdb"
-	"github.com/PowerDNS/lmdb-go/lmdbscan"
 	"github.com/go-gl/glfw/v3.3/glfw"
 
+	"github.com/zshimonz/lmdb-gui-client/cli"
+	"github.com/zshimonz/lmdb-gui-client/codec"
 	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
 	mytheme "github.com/zshimonz/lmdb-gui-client/theme"
 )
 
 var env *lmdb.Env
 var dbi lmdb.DBI
 var keyValues []KeyValue
+
+// keyValuesLock 保护 keyValues。值搜索等后台协程会替换表格内容，表格的回调可能在绘制协程中调用，
+// 读取和替换 keyValues 时都需要持有；持有期间不要调用控件的方法
+var keyValuesLock sync.Mutex
 var selectedKey string
 var windowWidth float32
 var windowHeight float32
-var connectionList *widget.List
+var mainWindow fyne.Window
+var connectionList *widget.Tree
 var keyValueTable *widget.Table
 
 var valueView *widget.Entry
 var selectedConnectionIndex = -1
+var selectedDBIName = store.RootDBIName
+var dbiNames = make(map[int][]string)
+var connectionReadOnly bool
+var writeButtons []*widget.Button
+var readOnlyBadge *fyne.Container
 var valueLabelString = binding.NewString()
 
 var connectionsPanel *fyne.Container
 var valuePanel *fyne.Container
+var valueContent *fyne.Container
+var dupValuesSplit *container.Split
 var leftMainSplit *container.Split
 
 var valuePanelOpen = false
@@ -62,13 +79,28 @@ var keyValuesTabItem *container.Split
 var editConnectionNameEntry *widget.Entry
 var editConnectionPathEntry *widget.Entry
 var editConnectionMapSizeEntry *widget.Entry
+var editConnectionMaxDBsEntry *widget.Entry
+var editConnectionReadOnlyCheck *widget.Check
+var editConnectionKeyLayoutEntry *widget.SelectEntry
+var editConnectionProtoEntries *protoSettingsEntries
 var editConnectionIndex int
 var toggleConnectionsButton *widget.Button
 
 var keyPrefix = binding.NewString()
+var keyCodec, _ = codec.ParseKeyCodec(codec.DefaultKeyLayout)
 var hideKeyPrefix = binding.NewBool()
 
+// 键范围过滤：起始键（包含）、结束键（不包含）和遍历方向
+var rangeStart = binding.NewString()
+var rangeEnd = binding.NewString()
+var descendingOrder = binding.NewBool()
+
 var currentPage = 1
+
+// 当前显示的页码及其第一个和最后一个键，翻页时从这些键开始定位，不必从头扫描
+var loadedPage int
+var loadedDescending bool
+var pageFirstKey, pageLastKey []byte
 var pageSize = 20
 var totalPage = 1
 var pageLabel *widget.Label
@@ -83,11 +115,19 @@ var oneCharWidth float32
 var hideValues = binding.NewBool()
 
 type KeyValue struct {
-	Key   string
-	Value string
+	Key       string
+	Value     string
+	Count     int    // DupSort 数据库中该键的重复值数量
+	RawKey    []byte // 键的原始字节，Key 为按键编码布局格式化后的文本
+	ValueHash uint64 // 原始值的哈希，自动刷新时用于判断值是否变化
 }
 
 func main() {
+	// 带子命令运行时作为命令行工具使用，不打开窗口
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	a := app.New()
 
 	lightTheme := &mytheme.MyLightTheme{}
@@ -96,6 +136,7 @@ func main() {
 	a.Settings().SetTheme(darkTheme)
 
 	w := a.NewWindow("LMDB GUI Client")
+	mainWindow = w
 
 	oneCharWidth = fyne.MeasureText("W", theme.TextSize(), fyne.TextStyle{}).Width
 
@@ -110,77 +151,97 @@ func main() {
 		showErrorLog("Error loading config: " + err.Error())
 	}
 
-	// 左侧布局：Connection 列表
-	connectionList = widget.NewList(
-		func() int { return len(config.Config.Connections) },
-		func() fyne.CanvasObject {
+	// 左侧布局：Connection 树，每个连接下展示其命名数据库
+	connectionList = widget.NewTree(
+		func(uid widget.TreeNodeID) []widget.TreeNodeID {
+			if uid == "" {
+				ids := make([]widget.TreeNodeID, len(config.Config.Connections))
+				for i := range config.Config.Connections {
+					ids[i] = strconv.Itoa(i)
+				}
+				return ids
+			}
+			connectionIndex, _, isDBI := parseConnectionNodeID(uid)
+			if isDBI {
+				return nil
+			}
+			ids := []widget.TreeNodeID{dbiNodeID(connectionIndex, store.RootDBIName)}
+			for _, name := range dbiNames[connectionIndex] {
+				ids = append(ids, dbiNodeID(connectionIndex, name))
+			}
+			return ids
+		},
+		func(uid widget.TreeNodeID) bool {
+			_, _, isDBI := parseConnectionNodeID(uid)
+			return uid == "" || !isDBI
+		},
+		func(branch bool) fyne.CanvasObject {
 			label := widget.NewLabel("")
 			label.Alignment = fyne.TextAlignLeading
+			if !branch {
+				return container.NewBorder(nil, nil, widget.NewIcon(theme.StorageIcon()), nil, label)
+			}
 			toolbar := widget.NewToolbar(
 				widget.NewToolbarAction(theme.DocumentCreateIcon(), func() {}),
 				widget.NewToolbarAction(theme.DeleteIcon(), func() {}),
-				widget.NewToolbarAction(theme.ContentClearIcon(), func() { connectionList.UnselectAll() }),
+				widget.NewToolbarAction(theme.DownloadIcon(), func() {}),
+				widget.NewToolbarAction(theme.ContentClearIcon(), func() {}),
 			)
 
 			return container.NewBorder(nil, nil, label, toolbar)
 		},
-		func(i widget.ListItemID, o fyne.CanvasObject) {
+		func(uid widget.TreeNodeID, branch bool, o fyne.CanvasObject) {
+			i, dbiName, isDBI := parseConnectionNodeID(uid)
+			if i < 0 || i >= len(config.Config.Connections) {
+				return
+			}
+			if isDBI {
+				label := o.(*fyne.Container).Objects[0].(*widget.Label)
+				if dbiName == store.RootDBIName {
+					label.SetText("(root)")
+				} else {
+					label.SetText(dbiName)
+				}
+				return
+			}
+
 			label := o.(*fyne.Container).Objects[0].(*widget.Label)
 			label.SetText(config.Config.Connections[i].Name)
 
 			toolbar := o.(*fyne.Container).Objects[1].(*widget.Toolbar)
 			editButton := toolbar.Items[0].(*widget.ToolbarAction)
 			editButton.OnActivated = func() {
-				if selectedConnectionIndex == i {
-					connectionList.UnselectAll()
-				}
+				// 修改连接前关闭其环境
+				closeConnection(i)
 				showEditConnectionTabItem(i)
 				toggleConnectionsButton.Disable()
 			}
 			deleteButton := toolbar.Items[1].(*widget.ToolbarAction)
 			deleteButton.OnActivated = func() {
-				if selectedConnectionIndex == i {
-					connectionList.UnselectAll()
-				}
 				// show confirm dialog
 				dialog.ShowConfirm("Delete Connection", "Are you sure you want to delete this connection?", func(b bool) {
 					if b {
+						closeConnection(i)
 						deleteConnection(i, connectionList)
-						if selectedConnectionIndex == i {
-							selectedConnectionIndex = -1
-							keyValueTable.UnselectAll()
-						}
 					}
 				}, w)
 			}
+			closeButton := toolbar.Items[3].(*widget.ToolbarAction)
+			closeButton.OnActivated = func() {
+				closeConnection(i)
+			}
+			backupButton := toolbar.Items[2].(*widget.ToolbarAction)
+			backupButton.OnActivated = func() {
+				showBackupDialog(w, i)
+			}
 			toolbar.Refresh()
 		},
 	)
 
-	connectionList.OnSelected = func(id widget.ListItemID) {
-		if err := keyPrefix.Set(""); err != nil {
-			return
-		}
-		err = connectToDB(id, true)
-		if err == nil {
-			selectedConnectionIndex = id
-			// hide mainValueSplit
-			keyValuesTabItem.Hidden = false
-		} else {
-			connectionList.UnselectAll()
-		}
-	}
-
-	connectionList.OnUnselected = func(id widget.ListItemID) {
-		selectedConnectionIndex = -1
-		// show mainValueSplit
-		keyValuesTabItem.Hidden = true
-		keyValueTable.UnselectAll()
-		err := env.Close()
-		if err != nil {
-			showErrorLog("Error closing LMDB environment: " + err.Error())
-			return
-		}
+	// 选中连接或数据库时在标签页中打开，已打开的连接切换到其标签页；连接在关闭标签页时才关闭
+	connectionList.OnSelected = func(uid widget.TreeNodeID) {
+		id, dbiName, _ := parseConnectionNodeID(uid)
+		selectConnection(id, dbiName)
 	}
 
 	// 主下侧布局：Value 多功能区
@@ -199,14 +260,42 @@ func main() {
 
 	updateButton := widget.NewButtonWithIcon("Update", theme.ConfirmIcon(), func() {
 		if selectedKey != "" {
-			insertOrUpdateKeyValue(selectedKey, valueView.Text)
+			// 未修改的值不会被写回，避免二进制值被改写
+			if !valueModified() {
+				showInfoLog("Value unchanged")
+				keyValueTable.UnselectAll()
+				return
+			}
+			value, err := valueViewBytes()
+			if err != nil {
+				showErrorLog("Invalid value: " + err.Error())
+				return
+			}
+			if dbiIsDupSort {
+				// DupSort 数据库中直接写入会新增一个重复值，需要替换选中的值
+				if selectedDupIndex < 0 {
+					showErrorLog("No duplicate value selected")
+					return
+				}
+				replaceDupValue(selectedKey, dupValues[selectedDupIndex], string(value))
+			} else {
+				insertOrUpdateKeyValue(selectedKey, string(value))
+			}
 			keyValueTable.UnselectAll()
 		}
 	})
 
 	deleteButton := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
 		if selectedKey != "" {
-			deleteKeyValue(selectedKey)
+			if dbiIsDupSort {
+				if selectedDupIndex < 0 {
+					showErrorLog("No duplicate value selected")
+					return
+				}
+				deleteDupValue(selectedKey, dupValues[selectedDupIndex])
+			} else {
+				deleteKeyValue(selectedKey)
+			}
 			keyValueTable.UnselectAll()
 		}
 	})
@@ -215,17 +304,24 @@ func main() {
 		keyValueTable.UnselectAll()
 	})
 
+	writeButtons = append(writeButtons, updateButton, deleteButton)
 	valueControls := container.NewGridWithColumns(3, updateButton, deleteButton, cancelButton)
 	copyLabelButton := widget.NewButtonWithIcon("Copy Key", theme.ContentCopyIcon(), func() {
 		fyne.CurrentApp().Driver().AllWindows()[0].Clipboard().SetContent(valueLabel.Text[5:])
 		showInfoLog("Key copied to clipboard!")
 	})
-	valuePanel = container.NewBorder(container.NewBorder(nil, nil, container.NewHBox(valueLabel, copyLabelButton), hideButton, nil), valueControls, nil, nil, valueView)
+	// DupSort 数据库在值编辑框上方显示重复值列表
+	dupValuesSplit = container.NewVSplit(initDupValuesPanel(valueView), valueView)
+	valueContent = container.NewStack(valueView)
+	valuePanel = container.NewBorder(container.NewBorder(nil, nil, container.NewHBox(valueLabel, copyLabelButton), container.NewHBox(initValueModeSelect(), hideButton), nil), valueControls, nil, nil, valueContent)
 	valuePanel.Hidden = true
 
 	keyValueTable = widget.NewTableWithHeaders(
 		func() (int, int) {
-			return len(keyValues), 2
+			if dbiIsDupSort {
+				return keyValueCount(), 3
+			}
+			return keyValueCount(), 2
 		},
 		func() fyne.CanvasObject {
 			newLabel := widget.NewLabel("")
@@ -234,31 +330,41 @@ func main() {
 		},
 		func(i widget.TableCellID, o fyne.CanvasObject) {
 			label := o.(*widget.Label)
-			if i.Col == 0 {
-				label.SetText(keyValues[i.Row].Key)
-			} else { // 1
-				label.SetText(keyValues[i.Row].Value)
+			keyValue, ok := keyValueAt(i.Row)
+			if !ok {
+				label.SetText("")
+				return
+			}
+			// 最近一次自动刷新时变化的行高亮显示
+			if keyChanged(string(keyValue.RawKey)) {
+				label.Importance = widget.HighImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+			switch i.Col {
+			case 0:
+				label.SetText(keyValue.Key)
+			case 1:
+				label.SetText(keyValue.Value)
+			default: // 2
+				if keyValue.Count > 0 {
+					label.SetText(strconv.Itoa(keyValue.Count))
+				} else {
+					// 值搜索的结果不统计重复值数量
+					label.SetText("")
+				}
 			}
 		},
 	)
 
 	keyValueTable.OnSelected = func(id widget.TableCellID) {
-		if id.Row < 0 || id.Row >= len(keyValues) || id.Col < 0 {
+		keyValue, ok := keyValueAt(id.Row)
+		if !ok || id.Col < 0 {
 			return
 		}
-		isHide, err := hideKeyPrefix.Get()
-		if err != nil {
-			return
-		}
-		selectedKey = keyValues[id.Row].Key
-		if isHide {
-			prefix, err := keyPrefix.Get()
-			if err != nil {
-				return
-			}
-			selectedKey = prefix + selectedKey
-		}
-		if err := valueLabelString.Set("Key: " + selectedKey); err != nil {
+		selectedKey = string(keyValue.RawKey)
+		selectedKeyCell = id
+		if err := valueLabelString.Set("Key: " + keyCodec.Format(keyValue.RawKey)); err != nil {
 			return
 		}
 		refreshValueView(valueView)
@@ -276,7 +382,7 @@ func main() {
 		if err != nil {
 			return
 		}
-		valueView.SetText("")
+		clearValueView()
 		if valuePanelOpen {
 			toggleValue()
 		}
@@ -286,10 +392,13 @@ func main() {
 
 	keyValueTable.UpdateHeader = func(id widget.TableCellID, template fyne.CanvasObject) {
 		label := template.(*widget.Label)
-		if id.Col == 0 {
+		switch id.Col {
+		case 0:
 			label.SetText("Key")
-		} else {
+		case 1:
 			label.SetText("Value")
+		default:
+			label.SetText("Dups")
 		}
 	}
 	// update column header
@@ -315,12 +424,52 @@ func main() {
 		keyValueTable.UnselectAll()
 	})
 
+	// 对前缀下的所有键执行删除、复制或移动
+	prefixOperationButton := widget.NewButtonWithIcon("Bulk", theme.ContentCutIcon(), func() {
+		showPrefixOperationDialog(w)
+	})
+	writeButtons = append(writeButtons, prefixOperationButton)
+
 	keyPrefixLabel := widget.NewLabel("Key Prefix:")
 	keyPrefixLabel.Alignment = fyne.TextAlignLeading
 
 	keyPrefixIcon := widget.NewIcon(theme.SearchIcon())
 	keyPrefixLabels := container.NewHBox(keyPrefixIcon, keyPrefixLabel)
 
+	// 键范围：[Start, End) 与前缀过滤同时生效，End 留空表示不限制
+	applyKeyRange := func() {
+		currentPage = 1
+		totalRecordsCached = false
+		loadKeyValues(keyPrefixEntry.Text, false)
+		keyValueTable.UnselectAll()
+	}
+	rangeStartEntry := widget.NewEntryWithData(rangeStart)
+	rangeStartEntry.SetPlaceHolder("Start key (inclusive)")
+	rangeStartEntry.OnSubmitted = func(s string) {
+		applyKeyRange()
+	}
+	rangeEndEntry := widget.NewEntryWithData(rangeEnd)
+	rangeEndEntry.SetPlaceHolder("End key (exclusive)")
+	rangeEndEntry.OnSubmitted = func(s string) {
+		applyKeyRange()
+	}
+	descendingCheck := widget.NewCheckWithData("Descending", descendingOrder)
+	descendingCheck.OnChanged = func(b bool) {
+		_ = descendingOrder.Set(b)
+		// 切换标签页恢复排序方向时不重新从第一页加载
+		if selectedConnectionIndex != -1 && b != loadedDescending {
+			applyKeyRange()
+		}
+	}
+	clearKeyRangeButton := widget.NewButtonWithIcon("Clear", theme.CancelIcon(), func() {
+		_ = rangeStart.Set("")
+		_ = rangeEnd.Set("")
+		applyKeyRange()
+	})
+	keyRangeLabels := container.NewHBox(widget.NewIcon(theme.ListIcon()), widget.NewLabel("Key Range:"))
+	keyRanges := container.NewBorder(nil, nil, keyRangeLabels, container.NewHBox(descendingCheck, clearKeyRangeButton),
+		container.NewGridWithColumns(2, rangeStartEntry, rangeEndEntry))
+
 	refreshKeysButton := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
 		currentPage = 1
 		totalRecordsCached = false
@@ -336,6 +485,24 @@ func main() {
 	newKeyButton := widget.NewButtonWithIcon("New", theme.ContentAddIcon(), func() {
 		showNewKeyValesTabItem()
 	})
+	writeButtons = append(writeButtons, newKeyButton)
+
+	exportButton := widget.NewButtonWithIcon("Export", theme.DocumentSaveIcon(), func() {
+		showExportDialog(w)
+	})
+	importButton := widget.NewButtonWithIcon("Import", theme.FolderOpenIcon(), func() {
+		showImportDialog(w)
+	})
+	writeButtons = append(writeButtons, importButton)
+	statsButton := widget.NewButtonWithIcon("Stats", theme.InfoIcon(), func() {
+		showStatsTabItem()
+	})
+	compareButton := widget.NewButtonWithIcon("Compare", theme.ViewRestoreIcon(), func() {
+		showCompareTabItem()
+	})
+	transformButton := widget.NewButtonWithIcon("Transform", theme.DocumentCreateIcon(), func() {
+		showTransformTabItem()
+	})
 
 	err = tabTitle.Set("Key Values")
 	if err != nil {
@@ -361,23 +528,7 @@ func main() {
 		}
 	}
 
-	autoRefreshCheckbox := widget.NewCheck("Auto Refresh (5s)", nil)
-	autoRefreshCheckbox.Checked = false
-	go func() {
-		for {
-			time.Sleep(5 * time.Second)
-			if connectionList.Length() != 0 && selectedConnectionIndex != -1 && autoRefreshCheckbox.Checked {
-				// reconnect to db
-				currentPage = 1
-				totalRecordsCached = false
-
-				loadKeyValues(keyPrefixEntry.Text, true)
-				keyValueTable.UnselectAll()
-
-				showInfoLog("Auto Refresh success!")
-			}
-		}
-	}()
+	autoRefreshCheckbox, refreshIntervalSelect := initAutoRefresh()
 
 	hideValuesCheckbox := widget.NewCheckWithData("Hide Values", hideValues)
 	hideValuesCheckbox.OnChanged = func(b bool) {
@@ -402,7 +553,7 @@ func main() {
 	pageSizeList.Selected = "20"
 	pageSizeList.Alignment = fyne.TextAlignCenter
 
-	refreshUnselectNewGrid := container.NewGridWithColumns(6, newKeyButton, unselectKeysButton, refreshKeysButton,
+	refreshUnselectNewGrid := container.NewGridWithColumns(11, newKeyButton, unselectKeysButton, refreshKeysButton, exportButton, importButton, statsButton, compareButton, transformButton,
 		container.NewCenter(hideKeyPrefixCheckbox), container.NewCenter(autoRefreshCheckbox), container.NewCenter(hideValuesCheckbox))
 
 	// 添加标题栏左侧的两个按钮
@@ -420,8 +571,7 @@ func main() {
 
 	// 初始化分页控件
 	pageLabel = widget.NewLabel("Page 1 / 1")
-	recordCountLabel = widget.NewLabel("Records: 0")
-	recordCountLabel.Alignment = fyne.TextAlignCenter
+	recordCount := initRecordCount()
 
 	pageLabel.Alignment = fyne.TextAlignCenter
 	firstButton := widget.NewButton("First", func() {
@@ -431,6 +581,10 @@ func main() {
 		}
 	})
 	lastButton := widget.NewButton("Last", func() {
+		if !totalRecordsKnown() {
+			showInfoLog("Records are still being counted")
+			return
+		}
 		if currentPage < totalPage {
 			currentPage = totalPage
 			loadKeyValues(keyPrefixEntry.Text, false)
@@ -444,7 +598,8 @@ func main() {
 		}
 	})
 	nextButton := widget.NewButton("Next", func() {
-		if currentPage < totalPage {
+		// 总数未知时，当前页已满就可以继续向后翻页
+		if currentPage < totalPage || (!totalRecordsKnown() && keyValueCount() == pageSize) {
 			currentPage++
 			loadKeyValues(keyPrefixEntry.Text, false)
 		}
@@ -455,7 +610,7 @@ func main() {
 
 	goToPageButton := widget.NewButton("Go", func() {
 		page, err := strconv.Atoi(pageEntry.Text)
-		if err == nil && page > 0 && page <= totalPage {
+		if err == nil && page > 0 && (page <= totalPage || !totalRecordsKnown()) {
 			currentPage = page
 			loadKeyValues(keyPrefixEntry.Text, false)
 		} else {
@@ -468,10 +623,11 @@ func main() {
 	}
 	pageEntry.Resize(fyne.NewSize(100, 36))
 
-	paginationControls := container.NewGridWithColumns(7, firstButton, prevButton, pageLabel, recordCountLabel, nextButton, lastButton, container.NewGridWithColumns(2, pageEntry, goToPageButton))
+	paginationControls := container.NewGridWithColumns(7, firstButton, prevButton, pageLabel, recordCount, nextButton, lastButton, container.NewGridWithColumns(2, pageEntry, goToPageButton))
 
-	keyPrefixes := container.NewBorder(nil, nil, keyPrefixLabels, container.NewHBox(clearKeyPrefixButton, container.NewBorder(nil, nil, widget.NewLabel("Page Size:"), nil, pageSizeList)), keyPrefixEntry)
-	keyValuesControls := container.NewBorder(nil, refreshUnselectNewGrid, nil, nil, keyPrefixes)
+	keyPrefixes := container.NewBorder(nil, nil, keyPrefixLabels, container.NewHBox(clearKeyPrefixButton, prefixOperationButton, container.NewBorder(nil, nil, widget.NewLabel("Page Size:"), nil, pageSizeList),
+		container.NewBorder(nil, nil, widget.NewLabel("Refresh Every:"), nil, refreshIntervalSelect)), keyPrefixEntry)
+	keyValuesControls := container.NewBorder(nil, refreshUnselectNewGrid, nil, nil, container.NewVBox(keyPrefixes, keyRanges, initSearchBar()))
 	keyValuesList := container.NewBorder(keyValuesControls, paginationControls, nil, nil, keyValueTable)
 
 	connectConnectionButton := widget.NewButtonWithIcon("New Connection", theme.ContentAddIcon(), func() {
@@ -496,11 +652,30 @@ func main() {
 
 	editConnectionTabItem = initEditConnectionTabItem(w)
 
-	tabTitles := container.NewBorder(nil, nil, toggleConnectionsButton, switchThemeButton, tabTitleLabel)
+	statsTabItem = initStatsTabItem()
+
+	readersTabItem = initReadersTabItem()
+
+	pendingTabItem = initPendingTabItem(w)
+
+	historyTabItem = initHistoryTabItem()
+
+	compareTabItem = initCompareTabItem(w)
 
-	tabView = container.NewStack(keyValuesTabItem, newConnectionTabItem, newKeyValesTabItem, editConnectionTabItem)
+	transformTabItem = initTransformTabItem(w)
+	initHistoryShortcuts(w)
 
-	tabContent := container.NewBorder(tabTitles, nil, nil, nil, tabView)
+	// 只读连接在标题栏显示标记
+	readOnlyLabel := widget.NewLabel("READ ONLY")
+	readOnlyLabel.TextStyle = fyne.TextStyle{Bold: true}
+	readOnlyBadge = container.NewHBox(widget.NewIcon(theme.WarningIcon()), readOnlyLabel)
+	readOnlyBadge.Hide()
+
+	tabTitles := container.NewBorder(nil, nil, toggleConnectionsButton, container.NewHBox(readOnlyBadge, initStagingControls(), switchThemeButton), tabTitleLabel)
+
+	tabView = container.NewStack(keyValuesTabItem, newConnectionTabItem, newKeyValesTabItem, editConnectionTabItem, statsTabItem, readersTabItem, pendingTabItem, historyTabItem, compareTabItem, transformTabItem)
+
+	tabContent := container.NewBorder(container.NewVBox(tabTitles, initConnectionTabs()), nil, nil, nil, tabView)
 
 	// 创建主布局，将左侧面板和主拆分器组合在一起
 	leftMainSplit = container.NewHSplit(connectionsPanel, tabContent)
@@ -531,19 +706,16 @@ func main() {
 }
 
 func refreshValueView(valueView *widget.Entry) {
+	if dbiIsDupSort {
+		loadDupValues(selectedKey, nil, false)
+		return
+	}
 	err := env.View(func(txn *lmdb.Txn) error {
 		val, err := txn.Get(dbi, []byte(selectedKey))
 		if err != nil {
 			return err
 		}
-		var formattedJSON map[string]interface{}
-		err = json.Unmarshal(val, &formattedJSON)
-		if err != nil {
-			valueView.SetText(string(val))
-		} else {
-			prettyJSON, _ := json.MarshalIndent(formattedJSON, "", "  ")
-			valueView.SetText(string(prettyJSON))
-		}
+		setValueViewBytes(append([]byte(nil), val...))
 		return nil
 	})
 	if err != nil {
@@ -551,8 +723,30 @@ func refreshValueView(valueView *widget.Entry) {
 	}
 }
 
-func deleteConnection(connectionIndex int, connectionList *widget.List) {
+// formatValue 将值转换为显示文本，JSON 对象会被格式化
+func formatValue(val []byte) string {
+	var formattedJSON map[string]interface{}
+	err := json.Unmarshal(val, &formattedJSON)
+	if err != nil {
+		return string(val)
+	}
+	prettyJSON, _ := json.MarshalIndent(formattedJSON, "", "  ")
+	return string(prettyJSON)
+}
+
+func deleteConnection(connectionIndex int, connectionList *widget.Tree) {
 	config.Config.Connections = append(config.Config.Connections[:connectionIndex], config.Config.Connections[connectionIndex+1:]...)
+	// 连接下标发生变化，清空已缓存的数据库列表，按下标保存的待提交修改移到新的下标下
+	dbiNames = make(map[int][]string)
+	shiftStagedChanges(connectionIndex)
+	resetCompare()
+	shiftSessions(connectionIndex)
+	// 已打开的连接重新列出命名数据库
+	for _, session := range sessions {
+		if names, err := store.ListDBINames(sessionEnv(session.connectionIndex), config.Config.Connections[session.connectionIndex].MaxDBs); err == nil {
+			dbiNames[session.connectionIndex] = names
+		}
+	}
 	err := config.SaveConfig()
 	if err != nil {
 		showErrorLog("Error saving config: " + err.Error())
@@ -560,6 +754,28 @@ func deleteConnection(connectionIndex int, connectionList *widget.List) {
 	connectionList.Refresh()
 }
 
+// dbiNodeID 生成连接树中数据库节点的 ID，格式为 "连接下标/数据库名"
+func dbiNodeID(connectionIndex int, name string) widget.TreeNodeID {
+	return strconv.Itoa(connectionIndex) + "/" + name
+}
+
+// parseConnectionNodeID 解析连接树节点 ID，返回连接下标、数据库名以及是否为数据库节点
+func parseConnectionNodeID(uid widget.TreeNodeID) (int, string, bool) {
+	index, name, isDBI := strings.Cut(uid, "/")
+	connectionIndex, err := strconv.Atoi(index)
+	if err != nil {
+		return -1, store.RootDBIName, false
+	}
+	return connectionIndex, name, isDBI
+}
+
+func dbiDisplayName(name string) string {
+	if name == store.RootDBIName {
+		return "(root)"
+	}
+	return name
+}
+
 func connectToDB(connectionIndex int, load bool) error {
 	if len(config.Config.Connections) == 0 {
 		showErrorLog("No database path configured")
@@ -567,38 +783,53 @@ func connectToDB(connectionIndex int, load bool) error {
 	}
 	connection := config.Config.Connections[connectionIndex]
 
+	// 值搜索、后台计数、自动刷新和执行中的脚本使用旧的环境，关闭前先停止
+	stopSearch()
+	stopCount()
+	stopAutoRefresh()
+	stopTransform()
+	releaseCompareEnv(connectionIndex)
+	if env != nil {
+		// 重新连接前关闭旧的环境，已关闭时忽略错误
+		_ = env.Close()
+	}
+
 	var err error
-	env, err = lmdb.NewEnv()
+	env, err = store.OpenEnv(connection)
 	if err != nil {
-		showErrorLog("Error creating LMDB environment: " + err.Error())
+		showErrorLog("Error opening LMDB database: " + err.Error())
 		return err
 	}
 
-	err = env.SetMapSize(1 << 30 * connection.MapSize)
+	names, err := store.ListDBINames(env, connection.MaxDBs)
 	if err != nil {
-		showErrorLog("Error setting LMDB map size: " + err.Error())
+		showErrorLog("Error listing LMDB databases: " + err.Error())
 		return err
 	}
+	dbiNames[connectionIndex] = names
+	setReadOnlyMode(connection.ReadOnly)
+	loadProtoSchema(connection)
+	loadKeyCodec(connection)
+	loadHistory(connection)
 
-	err = env.SetMaxDBs(0)
+	dbi, err = store.OpenDBI(env, selectedDBIName)
 	if err != nil {
-		showErrorLog("Error setting LMDB max DBs: " + err.Error())
+		showErrorLog("Error opening LMDB database " + dbiDisplayName(selectedDBIName) + ": " + err.Error())
 		return err
 	}
-	err = env.Open(connection.DatabasePath, 0, 0664)
+	dbiIsDupSort, err = store.IsDupSort(env, dbi)
 	if err != nil {
-		showErrorLog("Error opening LMDB database: " + err.Error())
+		showErrorLog("Error reading LMDB database flags: " + err.Error())
 		return err
 	}
-	err = env.Update(func(txn *lmdb.Txn) (err error) {
-		dbi, err = txn.OpenRoot(0)
-		return err
-	})
-	if err != nil {
-		showErrorLog("Error opening LMDB root: " + err.Error())
-		return err
+	if dbiIsDupSort {
+		valueContent.Objects = []fyne.CanvasObject{dupValuesSplit}
+	} else {
+		valueContent.Objects = []fyne.CanvasObject{valueView}
 	}
+	valueContent.Refresh()
 	showInfoLog("Database connected")
+	startAutoRefresh()
 
 	currentPage = 1
 	totalRecordsCached = false
@@ -608,90 +839,79 @@ func connectToDB(connectionIndex int, load bool) error {
 	return nil
 }
 
+// loadKeyCodec 按连接配置的键编码布局设置 keyCodec，布局无效时回退为 UTF-8
+func loadKeyCodec(connection config.ConnectionConfig) {
+	var err error
+	keyCodec, err = codec.ParseKeyCodec(connection.KeyLayout)
+	if err != nil {
+		showErrorLog("Invalid key layout: " + err.Error())
+		keyCodec, _ = codec.ParseKeyCodec(codec.DefaultKeyLayout)
+	}
+}
+
+// currentKeyRange 按键编码布局解析前缀和范围输入，得到当前的键范围
+func currentKeyRange(prefixText string) (store.KeyRange, error) {
+	var keyRange store.KeyRange
+	var err error
+	if keyRange.Prefix, err = keyCodec.ParsePrefix(prefixText); err != nil {
+		return keyRange, fmt.Errorf("Invalid key prefix: %w", err)
+	}
+	startText, _ := rangeStart.Get()
+	if keyRange.Start, err = keyCodec.ParsePrefix(startText); err != nil {
+		return keyRange, fmt.Errorf("Invalid range start: %w", err)
+	}
+	endText, _ := rangeEnd.Get()
+	if keyRange.End, err = keyCodec.ParsePrefix(endText); err != nil {
+		return keyRange, fmt.Errorf("Invalid range end: %w", err)
+	}
+	keyRange.Descending, _ = descendingOrder.Get()
+	return keyRange, nil
+}
+
 func loadKeyValues(keyPrefix string, reconnectDB bool) {
+	// 重新加载会替换表格内容，先停止正在进行的值搜索
+	stopSearch()
+	searchResultsShown = false
+	setChangedKeys(nil)
 	if reconnectDB {
 		_ = connectToDB(selectedConnectionIndex, false)
 	}
-	err := env.View(func(txn *lmdb.Txn) error {
-		scanner := lmdbscan.New(txn, dbi)
-
-		// 设置扫描器的起始位置
-		if keyPrefix != "" {
-			scanner.Set([]byte(keyPrefix), nil, lmdb.SetRange)
-		}
-		// 计算总记录数（仅在首次计算时）
+	// 前缀和范围输入按键编码布局转换为字节，例如 u64be 布局下输入 "42"
+	prefixText := keyPrefix
+	keyRange, err := currentKeyRange(prefixText)
+	if err != nil {
+		showErrorLog(err.Error())
+		return
+	}
+	err = env.View(func(txn *lmdb.Txn) error {
+		// 计算总记录数（仅在首次计算时）。不过滤时直接使用 Stat 中的记录数，否则在后台统计
 		if !totalRecordsCached {
-			totalRecords = 0
-			for scanner.Scan() {
-				key := scanner.Key()
-				if keyPrefix != "" && !strings.HasPrefix(string(key), keyPrefix) {
-					break
-				}
-				totalRecords++
-			}
 			totalRecordsCached = true
-
-			recordCountLabel.SetText("Records: " + strconv.Itoa(totalRecords))
-
-			// 清空扫描器，并重新设置起始位置
-			scanner.Close()
-			scanner = lmdbscan.New(txn, dbi)
-			if keyPrefix != "" {
-				scanner.Set([]byte(keyPrefix), nil, lmdb.SetRange)
-			}
-		}
-
-		totalPage = int(math.Ceil(float64(totalRecords) / float64(pageSize)))
-
-		pageLabel.SetText(fmt.Sprintf("Page %d / %d", currentPage, totalPage))
-
-		// 跳过前面页的数据
-		for i := 0; i < (currentPage-1)*pageSize; i++ {
-			if !scanner.Scan() {
-				break
-			}
-		}
-
-		// 读取当前页的数据
-		keyValues = make([]KeyValue, 0)
-		for i := 0; i < pageSize; i++ {
-			if !scanner.Scan() {
-				break
-			}
-
-			key := scanner.Key()
-			val := scanner.Val()
-
-			// 检查键前缀
-			maxLen := 195
-			if keyPrefix == "" || (len(key) >= len(keyPrefix) && string(key[:len(keyPrefix)]) == keyPrefix) {
-				displayVal := ""
-				isHide, _ := hideValues.Get()
-				if !isHide {
-					displayVal = string(val)
-					if len(displayVal) > maxLen {
-						displayVal = displayVal[:maxLen]
-					}
-				}
-
-				displayKey := string(key)
-				hidePrefix, err := hideKeyPrefix.Get()
+			if keyRange.IsEmpty() && !dbiIsDupSort {
+				stopCount()
+				count, err := store.CountRange(context.Background(), txn, dbi, keyRange, false)
 				if err != nil {
 					return err
 				}
-				if hidePrefix {
-					displayKey = displayKey[len(keyPrefix):]
-				}
-				keyValues = append(keyValues, KeyValue{Key: displayKey, Value: strings.ReplaceAll(displayVal, "\n", " ")})
-			} else if keyPrefix != "" && string(key) > keyPrefix {
-				// 如果当前键大于前缀，结束扫描
-				break
+				setTotalRecords(count)
+			} else {
+				startCount(keyRange)
 			}
 		}
 
-		if err := scanner.Err(); err != nil {
+		page, err := readPage(txn, keyRange, prefixText)
+		if err != nil {
 			return err
 		}
+		setKeyValues(page)
+		loadedPage, loadedDescending = currentPage, keyRange.Descending
+		pageFirstKey, pageLastKey = nil, nil
+		if len(page) > 0 {
+			pageFirstKey = page[0].RawKey
+			pageLastKey = page[len(page)-1].RawKey
+		}
+
+		updatePageLabel()
 
 		keyValueTable.Refresh()
 		adaptiveColumnWidths()
@@ -703,42 +923,228 @@ func loadKeyValues(keyPrefix string, reconnectDB bool) {
 	}
 }
 
-func insertOrUpdateKeyValue(key, value string) {
-	err := env.Update(func(txn *lmdb.Txn) error {
-		err := txn.Put(dbi, []byte(key), []byte(value), 0)
-		return err
-	})
-	if err != nil {
-		showErrorLog("Error insert/update key-value: " + err.Error())
-		return
+// readPage 读取 currentPage 页的记录。相邻页从上次显示的页的边界键开始定位，
+// 后半部分的页从范围的末尾反向读取，只有跳转到前半部分的页时才需要从头跳过前面的记录
+func readPage(txn *lmdb.Txn, keyRange store.KeyRange, prefixText string) ([]KeyValue, error) {
+	switch {
+	case currentPage == 1:
+		return readKeyValues(txn, keyRange, nil, false, 0, pageSize, prefixText)
+	case currentPage == loadedPage && pageFirstKey != nil:
+		// 重新加载当前页
+		return readKeyValues(txn, keyRange, pageFirstKey, true, 0, pageSize, prefixText)
+	case currentPage == loadedPage+1 && pageLastKey != nil:
+		return readKeyValues(txn, keyRange, pageLastKey, false, 0, pageSize, prefixText)
+	case currentPage == loadedPage-1 && pageFirstKey != nil:
+		page, err := readKeyValues(txn, keyRange.Reverse(), pageFirstKey, false, 0, pageSize, prefixText)
+		if err != nil || len(page) == pageSize {
+			return reverseKeyValues(page), err
+		}
+		// 前面的记录已不足一页（例如期间删除了记录），回到第一页
+		currentPage = 1
+		return readKeyValues(txn, keyRange, nil, false, 0, pageSize, prefixText)
+	case totalRecordsKnown() && currentPage-1 > totalPage-currentPage:
+		// 从末尾反向跳过后面各页的记录，最后一页可能不足 pageSize 条
+		skip := totalRecords - currentPage*pageSize
+		limit := pageSize
+		if skip < 0 {
+			limit += skip
+			skip = 0
+		}
+		page, err := readKeyValues(txn, keyRange.Reverse(), nil, false, skip, limit, prefixText)
+		return reverseKeyValues(page), err
+	default:
+		return readKeyValues(txn, keyRange, nil, false, (currentPage-1)*pageSize, pageSize, prefixText)
 	}
-	showInfoLog("Key-Value inserted/updated")
-	prefix, err := keyPrefix.Get()
+}
+
+// readKeyValues 按 keyRange 的方向读取最多 limit 条记录。from 不为 nil 时从 from 处（inclusive 为 true）或之后开始，
+// 否则从范围的起点开始；先跳过 skip 条记录
+func readKeyValues(txn *lmdb.Txn, keyRange store.KeyRange, from []byte, inclusive bool, skip, limit int, prefixText string) ([]KeyValue, error) {
+	cursor, err := store.NewKeyCursor(txn, dbi, keyRange, dbiIsDupSort)
 	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	next := cursor.Next
+	if from != nil {
+		next = func() ([]byte, []byte, bool, error) {
+			next = cursor.Next
+			return cursor.Seek(from, inclusive)
+		}
+	}
+	for i := 0; i < skip; i++ {
+		_, _, ok, err := next()
+		if err != nil || !ok {
+			return make([]KeyValue, 0), err
+		}
+	}
+
+	keyValues := make([]KeyValue, 0, limit)
+	for len(keyValues) < limit {
+		key, val, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		keyValue := newKeyValue(key, val, prefixText)
+		if dbiIsDupSort {
+			count, err := cursor.Cursor().Count()
+			if err != nil {
+				return nil, err
+			}
+			keyValue.Count = int(count)
+		}
+		keyValues = append(keyValues, keyValue)
+	}
+	return keyValues, nil
+}
+
+// queueUIEvent 将 fn 放入窗口的事件队列，在处理点击、输入等界面事件的协程中依次执行。
+// 后台协程需要重新加载键列表或修改分页状态时使用，避免与界面事件的处理函数同时修改
+func queueUIEvent(fn func()) {
+	queue, ok := mainWindow.(interface{ QueueEvent(func()) })
+	if !ok {
+		fn()
 		return
 	}
-	loadKeyValues(prefix, false)
+	defer func() {
+		// 窗口关闭后事件队列已经销毁，程序即将退出，忽略
+		_ = recover()
+	}()
+	queue.QueueEvent(fn)
+}
+
+// keyValueCount 返回表格中的行数
+func keyValueCount() int {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	return len(keyValues)
+}
+
+// keyValueAt 返回表格中的一行，row 超出范围时 ok 为 false
+func keyValueAt(row int) (keyValue KeyValue, ok bool) {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	if row < 0 || row >= len(keyValues) {
+		return keyValue, false
+	}
+	return keyValues[row], true
+}
+
+// currentKeyValues 返回表格当前的内容。keyValues 总是整体替换，不会原地修改，返回的切片可以直接遍历
+func currentKeyValues() []KeyValue {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	return keyValues
+}
+
+// setKeyValues 替换表格的内容，调用者随后需要刷新表格
+func setKeyValues(rows []KeyValue) {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	keyValues = rows
+}
+
+func reverseKeyValues(keyValues []KeyValue) []KeyValue {
+	for i, j := 0, len(keyValues)-1; i < j; i, j = i+1, j-1 {
+		keyValues[i], keyValues[j] = keyValues[j], keyValues[i]
+	}
+	return keyValues
+}
+
+// newKeyValue 生成表格中的一行，键按键编码布局格式化，值截断为单行
+func newKeyValue(key, val []byte, prefixText string) KeyValue {
+	maxLen := 195
+	displayVal := ""
+	isHide, _ := hideValues.Get()
+	if !isHide {
+		displayVal = codec.DisplayString(val)
+		if len(displayVal) > maxLen {
+			displayVal = displayVal[:maxLen]
+		}
+	}
+
+	displayKey := keyCodec.Format(key)
+	hidePrefix, _ := hideKeyPrefix.Get()
+	if hidePrefix {
+		displayKey = strings.TrimPrefix(displayKey, prefixText)
+	}
+	valueHash := fnv.New64a()
+	valueHash.Write(val)
+	return KeyValue{Key: displayKey, Value: strings.ReplaceAll(displayVal, "\n", " "), RawKey: append([]byte(nil), key...), ValueHash: valueHash.Sum64()}
+}
+
+func insertOrUpdateKeyValue(key, value string) {
+	writeChange(store.Change{Kind: store.ChangePut, Key: []byte(key), Value: []byte(value)},
+		"Key-Value inserted/updated", "Error insert/update key-value: ")
 }
 
 func deleteKeyValue(key string) {
-	err := env.Update(func(txn *lmdb.Txn) error {
-		err := txn.Del(dbi, []byte(key), nil)
-		return err
-	})
+	writeChange(store.Change{Kind: store.ChangeDelete, Key: []byte(key)},
+		"Key-Value deleted", "Error deleting key-value: ")
+}
+
+// writeChange 在一个写事务中写入一次修改；暂存模式下只加入待提交列表
+func writeChange(change store.Change, doneMessage, errorMessage string) {
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	if staging, _ := stagingMode.Get(); staging {
+		stageChange(change)
+		return
+	}
+	applied, err := store.ApplyChanges(env, dbi, []store.Change{change})
 	if err != nil {
-		showErrorLog("Error deleting key-value: " + err.Error())
+		showErrorLog(errorMessage + err.Error())
 		return
 	}
-	showInfoLog("Key-Value deleted")
+	recordHistory(applied)
+	showInfoLog(doneMessage)
+	reloadKeyValues()
+}
 
+// setReadOnlyMode 切换只读模式，禁用所有写入按钮并显示只读标记
+func setReadOnlyMode(readOnly bool) {
+	connectionReadOnly = readOnly
+	for _, button := range writeButtons {
+		if readOnly {
+			button.Disable()
+		} else {
+			button.Enable()
+		}
+	}
+	if readOnly {
+		readOnlyBadge.Show()
+	} else {
+		readOnlyBadge.Hide()
+	}
+}
+
+// reloadKeyValues 按当前的前缀过滤条件重新加载当前页
+func reloadKeyValues() {
 	prefix, err := keyPrefix.Get()
 	if err != nil {
 		return
 	}
-
 	loadKeyValues(prefix, false)
 }
 
+// queueReloadKeyValues 在后台写入完成后重新加载当前页。重新加载放入界面事件队列中执行，
+// 期间已切换到其他连接或数据库时不重新加载
+func queueReloadKeyValues(writeEnv *lmdb.Env, writeDBI lmdb.DBI) {
+	queueUIEvent(func() {
+		if env != writeEnv || dbi != writeDBI {
+			return
+		}
+		totalRecordsCached = false
+		reloadKeyValues()
+	})
+}
+
 func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 	editConnectionNameLabel := widget.NewLabel("Connection Name:")
 	editConnectionNameLabel.TextStyle = fyne.TextStyle{Monospace: true}
@@ -749,6 +1155,15 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 	editConnectionMapSizeLabel := widget.NewLabel("Map  Size  (GB) :")
 	editConnectionMapSizeLabel.TextStyle = fyne.TextStyle{Monospace: true}
 	editConnectionMapSizeEntry = widget.NewEntry()
+	editConnectionMaxDBsLabel := widget.NewLabel("Max   DBs      :")
+	editConnectionMaxDBsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	editConnectionMaxDBsEntry = widget.NewEntry()
+	editConnectionKeyLayoutLabel := widget.NewLabel("Key   Layout   :")
+	editConnectionKeyLayoutLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	editConnectionKeyLayoutEntry = widget.NewSelectEntry(codec.KeyLayouts())
+	editConnectionKeyLayoutEntry.SetPlaceHolder(codec.DefaultKeyLayout)
+	editConnectionReadOnlyCheck = widget.NewCheck("Read Only", nil)
+	editConnectionProtoEntries = newProtoSettingsEntries()
 
 	saveButton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
 		if editConnectionNameEntry.Text == "" {
@@ -768,6 +1183,20 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 			showErrorLog("Map size must be a non-negative integer")
 			return
 		}
+		if !isNonNegativeInteger(editConnectionMaxDBsEntry.Text) {
+			showErrorLog("Max DBs must be a non-negative integer")
+			return
+		}
+		keyLayout, err := parseKeyLayout(editConnectionKeyLayoutEntry.Text)
+		if err != nil {
+			showErrorLog("Invalid key layout: " + err.Error())
+			return
+		}
+		var protoSettings config.ConnectionConfig
+		if err := editConnectionProtoEntries.applyTo(&protoSettings); err != nil {
+			showErrorLog("Invalid protobuf settings: " + err.Error())
+			return
+		}
 
 		// try to open the database to check if it exists
 		envTest, err := lmdb.NewEnv()
@@ -775,7 +1204,7 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 			showErrorLog("Error creating LMDB environment: " + err.Error())
 			return
 		}
-		err = envTest.Open(editConnectionPathEntry.Text, 0, 0664)
+		err = envTest.Open(editConnectionPathEntry.Text, store.OpenFlags(editConnectionReadOnlyCheck.Checked), 0664)
 		if err != nil {
 			showErrorLog("Error opening LMDB database: " + err.Error())
 			return
@@ -801,7 +1230,7 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 			showErrorLog("Error creating LMDB environment: " + err.Error())
 			return
 		}
-		err = envTest.Open(editConnectionPathEntry.Text, 0, 0664)
+		err = envTest.Open(editConnectionPathEntry.Text, store.OpenFlags(editConnectionReadOnlyCheck.Checked), 0664)
 		if err != nil {
 			showErrorLog("Error opening LMDB database: " + err.Error())
 			return
@@ -813,6 +1242,14 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 		}
 
 		config.Config.Connections[editConnectionIndex].MapSize = mapSize
+		config.Config.Connections[editConnectionIndex].MaxDBs, _ = strconv.Atoi(editConnectionMaxDBsEntry.Text)
+		config.Config.Connections[editConnectionIndex].ReadOnly = editConnectionReadOnlyCheck.Checked
+		config.Config.Connections[editConnectionIndex].KeyLayout = keyLayout
+		config.Config.Connections[editConnectionIndex].ProtoFiles = protoSettings.ProtoFiles
+		config.Config.Connections[editConnectionIndex].ProtoImportPaths = protoSettings.ProtoImportPaths
+		config.Config.Connections[editConnectionIndex].DescriptorSets = protoSettings.DescriptorSets
+		config.Config.Connections[editConnectionIndex].ProtoMappings = protoSettings.ProtoMappings
+		delete(dbiNames, editConnectionIndex)
 		err = config.SaveConfig()
 		if err != nil {
 			showErrorLog("Error saving config: " + err.Error())
@@ -860,8 +1297,12 @@ func initEditConnectionTabItem(w fyne.Window) *fyne.Container {
 		container.NewBorder(nil, nil, editConnectionNameLabel, nil, editConnectionNameEntry),
 		container.NewBorder(nil, nil, editConnectionPathLabel, browseButton, editConnectionPathEntry),
 		container.NewBorder(nil, nil, editConnectionMapSizeLabel, nil, editConnectionMapSizeEntry),
-		container.NewGridWithColumns(2, saveButton, cancelButton),
+		container.NewBorder(nil, nil, editConnectionMaxDBsLabel, nil, editConnectionMaxDBsEntry),
+		container.NewBorder(nil, nil, editConnectionKeyLayoutLabel, nil, editConnectionKeyLayoutEntry),
 	)
+	border.Objects = append(border.Objects, editConnectionProtoEntries.rows()...)
+	border.Add(editConnectionReadOnlyCheck)
+	border.Add(container.NewGridWithColumns(2, saveButton, cancelButton))
 	border.Hide()
 	return border
 }
@@ -879,6 +1320,16 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 	mapSizeLabel.TextStyle = fyne.TextStyle{Monospace: true}
 	mapSizeEntry := widget.NewEntry()
 	mapSizeEntry.SetText("1")
+	maxDBsLabel := widget.NewLabel("Max   DBs      :")
+	maxDBsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	maxDBsEntry := widget.NewEntry()
+	maxDBsEntry.SetText("16")
+	keyLayoutLabel := widget.NewLabel("Key   Layout   :")
+	keyLayoutLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	keyLayoutEntry := widget.NewSelectEntry(codec.KeyLayouts())
+	keyLayoutEntry.SetPlaceHolder(codec.DefaultKeyLayout)
+	readOnlyCheck := widget.NewCheck("Read Only", nil)
+	protoEntries := newProtoSettingsEntries()
 
 	browseButton := widget.NewButtonWithIcon("Browse", theme.FolderNewIcon(), func() {
 		fd := dialog.NewFolderOpen(func(file fyne.ListableURI, err error) {
@@ -912,13 +1363,27 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 			showErrorLog("Map size must be a non-negative integer")
 			return
 		}
+		if !isNonNegativeInteger(maxDBsEntry.Text) {
+			showErrorLog("Max DBs must be a non-negative integer")
+			return
+		}
+		keyLayout, err := parseKeyLayout(keyLayoutEntry.Text)
+		if err != nil {
+			showErrorLog("Invalid key layout: " + err.Error())
+			return
+		}
+		var protoSettings config.ConnectionConfig
+		if err := protoEntries.applyTo(&protoSettings); err != nil {
+			showErrorLog("Invalid protobuf settings: " + err.Error())
+			return
+		}
 		// try to open the database to check if it exists
 		envTest, err := lmdb.NewEnv()
 		if err != nil {
 			showErrorLog("Error creating LMDB environment: " + err.Error())
 			return
 		}
-		err = envTest.Open(entry.Text, 0, 0664)
+		err = envTest.Open(entry.Text, store.OpenFlags(readOnlyCheck.Checked), 0664)
 		if err != nil {
 			showErrorLog("Error opening LMDB database: " + err.Error())
 			return
@@ -930,10 +1395,19 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 		}
 
 		mapSize, err := strconv.ParseInt(mapSizeEntry.Text, 10, 64)
+		maxDBs, _ := strconv.Atoi(maxDBsEntry.Text)
 		config.Config.Connections = append(config.Config.Connections, config.ConnectionConfig{
 			Name:         nameEntry.Text,
 			DatabasePath: entry.Text,
 			MapSize:      mapSize,
+			MaxDBs:       maxDBs,
+			ReadOnly:     readOnlyCheck.Checked,
+			KeyLayout:    keyLayout,
+
+			ProtoFiles:       protoSettings.ProtoFiles,
+			ProtoImportPaths: protoSettings.ProtoImportPaths,
+			DescriptorSets:   protoSettings.DescriptorSets,
+			ProtoMappings:    protoSettings.ProtoMappings,
 		})
 		err = config.SaveConfig()
 		if err != nil {
@@ -944,6 +1418,10 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 		nameEntry.SetText("")
 		entry.SetText("")
 		mapSizeEntry.SetText("1")
+		maxDBsEntry.SetText("16")
+		keyLayoutEntry.SetText("")
+		readOnlyCheck.SetChecked(false)
+		protoEntries.reset()
 		err = tabTitle.Set("Key Values")
 		if err != nil {
 			return
@@ -955,6 +1433,10 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 		nameEntry.SetText("")
 		entry.SetText("")
 		mapSizeEntry.SetText("1")
+		maxDBsEntry.SetText("16")
+		keyLayoutEntry.SetText("")
+		readOnlyCheck.SetChecked(false)
+		protoEntries.reset()
 		err := tabTitle.Set("Key Values")
 		if err != nil {
 			return
@@ -967,8 +1449,12 @@ func initNewConnectionTabItem(w fyne.Window) *fyne.Container {
 		container.NewBorder(nil, nil, nameLabel, nil, nameEntry),
 		container.NewBorder(nil, nil, entryLabel, browseButton, entry),
 		container.NewBorder(nil, nil, mapSizeLabel, nil, mapSizeEntry),
-		container.NewGridWithColumns(2, saveButton, cancelButton),
+		container.NewBorder(nil, nil, maxDBsLabel, nil, maxDBsEntry),
+		container.NewBorder(nil, nil, keyLayoutLabel, nil, keyLayoutEntry),
 	)
+	border.Objects = append(border.Objects, protoEntries.rows()...)
+	border.Add(readOnlyCheck)
+	border.Add(container.NewGridWithColumns(2, saveButton, cancelButton))
 	border.Hide()
 	return border
 }
@@ -981,7 +1467,12 @@ func initNewKeyValuesTableItem() *fyne.Container {
 	valueEntry.Wrapping = fyne.TextWrapWord
 
 	saveButton := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
-		insertOrUpdateKeyValue(keyEntry.Text, valueEntry.Text)
+		key, err := keyCodec.Parse(keyEntry.Text)
+		if err != nil {
+			showErrorLog("Invalid key: " + err.Error())
+			return
+		}
+		insertOrUpdateKeyValue(string(key), valueEntry.Text)
 		keyEntry.SetText("")
 		valueEntry.SetText("")
 		showKeyValesTabItem()
@@ -1013,6 +1504,12 @@ func showNewConnectionTabItem() {
 	editConnectionTabItem.Hide()
 	newKeyValesTabItem.Hide()
 	keyValuesTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
 }
 
 func showEditConnectionTabItem(i int) {
@@ -1026,11 +1523,21 @@ func showEditConnectionTabItem(i int) {
 	editConnectionNameEntry.SetText(connection.Name)
 	editConnectionPathEntry.SetText(connection.DatabasePath)
 	editConnectionMapSizeEntry.SetText(strconv.FormatInt(connection.MapSize, 10))
+	editConnectionMaxDBsEntry.SetText(strconv.Itoa(connection.MaxDBs))
+	editConnectionReadOnlyCheck.SetChecked(connection.ReadOnly)
+	editConnectionKeyLayoutEntry.SetText(connection.KeyLayout)
+	editConnectionProtoEntries.setConnection(connection)
 
 	newConnectionTabItem.Hide()
 	editConnectionTabItem.Show()
 	newKeyValesTabItem.Hide()
 	keyValuesTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
 
 	// close the connections panel
 	toggleConnections()
@@ -1040,6 +1547,12 @@ func showKeyValesTabItem() {
 	newKeyValesTabItem.Hide()
 	editConnectionTabItem.Hide()
 	newConnectionTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
 	keyValuesTabItem.Show()
 	err := tabTitle.Set("Key Values")
 	if err != nil {
@@ -1048,6 +1561,10 @@ func showKeyValesTabItem() {
 }
 
 func showNewKeyValesTabItem() {
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
 	err := tabTitle.Set("New Key-Value")
 	if err != nil {
 		return
@@ -1056,6 +1573,12 @@ func showNewKeyValesTabItem() {
 	editConnectionTabItem.Hide()
 	newConnectionTabItem.Hide()
 	keyValuesTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
 }
 
 func toggleConnections() {
@@ -1151,9 +1674,10 @@ func adaptiveColumnWidths() {
 	go func() {
 		// 预设列的最大宽度
 		maxKeyWidth := float32(300)
+		rows := currentKeyValues()
 
 		// 遍历所有的键值对，计算列的最大宽度
-		for _, keyValue := range keyValues {
+		for _, keyValue := range rows {
 			keyWidth := float32(len(keyValue.Key)) * oneCharWidth
 			if keyWidth > maxKeyWidth {
 				maxKeyWidth = keyWidth
@@ -1168,6 +1692,11 @@ func adaptiveColumnWidths() {
 
 		// 计算剩余的宽度并更新值为前缀那么多字
 		remainingWidth := keyValuesTabItem.Size().Width - maxKeyWidth
+		if dbiIsDupSort {
+			countWidth := oneCharWidth * 8
+			keyValueTable.SetColumnWidth(2, countWidth)
+			remainingWidth -= countWidth
+		}
 		minValueWidth := oneCharWidth * 30 // 计算30个字符的宽度
 
 		if remainingWidth < minValueWidth {
@@ -1177,16 +1706,16 @@ func adaptiveColumnWidths() {
 		// 使用WaitGroup来同步Goroutines
 		var wg sync.WaitGroup
 
-		updatedKeyValues := make([]KeyValue, len(keyValues))
+		updatedKeyValues := make([]KeyValue, len(rows))
 
-		results := make(chan KeyValue, len(keyValues))
+		results := make(chan KeyValue, len(rows))
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for _, keyValue := range keyValues {
-				prefixValue := truncateToFit(keyValue.Value, remainingWidth, oneCharWidth)
-				results <- KeyValue{Key: keyValue.Key, Value: prefixValue}
+			for _, keyValue := range rows {
+				keyValue.Value = truncateToFit(keyValue.Value, remainingWidth, oneCharWidth)
+				results <- keyValue
 			}
 		}()
 
@@ -1207,8 +1736,12 @@ func adaptiveColumnWidths() {
 		maxValueWidth := remainingWidth
 		keyValueTable.SetColumnWidth(1, maxValueWidth)
 
-		// 更新全局变量
-		keyValues = updatedKeyValues
+		// 更新全局变量，期间表格内容已被替换时不再覆盖
+		keyValuesLock.Lock()
+		if len(keyValues) == len(rows) && (len(rows) == 0 || &keyValues[0] == &rows[0]) {
+			keyValues = updatedKeyValues
+		}
+		keyValuesLock.Unlock()
 	}()
 }
 
@@ -1240,6 +1773,20 @@ func truncateToFit(value string, width float32, spaceWidth float32) string {
 	return value[:low]
 }
 
+// formatBytes 将字节数格式化为带单位的文本，例如 "1.5 GB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func isPositiveInteger(s string) bool {
 	// 尝试将字符串转换为整数
 	n, err := strconv.Atoi(s)
@@ -1249,3 +1796,23 @@ func isPositiveInteger(s string) bool {
 	// 检查整数是否为正数
 	return n > 0
 }
+
+// parseKeyLayout 校验并规范化键编码布局，默认的 UTF-8 布局保存为空
+func parseKeyLayout(layout string) (string, error) {
+	keyCodec, err := codec.ParseKeyCodec(layout)
+	if err != nil {
+		return "", err
+	}
+	if keyCodec.IsText() {
+		return "", nil
+	}
+	return keyCodec.String(), nil
+}
+
+func isNonNegativeInteger(s string) bool {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return false
+	}
+	return n >= 0
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// Dry Run 时列出的键的最大数量
+const prefixDryRunKeys = 100
+
+// showPrefixOperationDialog 对当前前缀过滤条件下的所有键执行删除、复制或移动
+func showPrefixOperationDialog(w fyne.Window) {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	prefixText, _ := keyPrefix.Get()
+	prefix, err := keyCodec.ParsePrefix(prefixText)
+	if err != nil {
+		showErrorLog("Invalid key prefix: " + err.Error())
+		return
+	}
+	if len(prefix) == 0 {
+		showErrorLog("Enter a key prefix first")
+		return
+	}
+
+	newPrefixEntry := widget.NewEntry()
+	newPrefixEntry.SetPlaceHolder("New key prefix")
+	operations := make([]string, 0, 3)
+	for _, op := range store.PrefixOperations() {
+		operations = append(operations, op.String())
+	}
+	operationSelect := widget.NewSelect(operations, func(s string) {
+		if s == store.PrefixDelete.String() {
+			newPrefixEntry.Disable()
+		} else {
+			newPrefixEntry.Enable()
+		}
+	})
+	operationSelect.SetSelected(store.PrefixDelete.String())
+	batchSizeEntry := widget.NewEntry()
+	batchSizeEntry.SetText("1000")
+	dryRunCheck := widget.NewCheck(fmt.Sprintf("Only list the first %d affected keys", prefixDryRunKeys), nil)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Database", widget.NewLabel(config.Config.Connections[selectedConnectionIndex].Name+" / "+dbiDisplayName(selectedDBIName))),
+		widget.NewFormItem("Key Prefix", widget.NewLabel(keyCodec.Format(prefix))),
+		widget.NewFormItem("Operation", operationSelect),
+		widget.NewFormItem("New Prefix", newPrefixEntry),
+		widget.NewFormItem("Batch Size", batchSizeEntry),
+		widget.NewFormItem("Dry Run", dryRunCheck),
+	}
+	form := dialog.NewForm("Prefix Operation", "Continue", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		op := store.PrefixOperations()[operationSelect.SelectedIndex()]
+		var newPrefix []byte
+		if op != store.PrefixDelete {
+			if newPrefix, err = keyCodec.ParsePrefix(newPrefixEntry.Text); err != nil {
+				showErrorLog("Invalid new prefix: " + err.Error())
+				return
+			}
+		}
+		if err := store.CheckPrefixOperation(op, prefix, newPrefix); err != nil {
+			showErrorLog("Error: " + err.Error())
+			return
+		}
+		if !isPositiveInteger(batchSizeEntry.Text) {
+			showErrorLog("Batch size must be a positive integer")
+			return
+		}
+		batchSize, _ := strconv.Atoi(batchSizeEntry.Text)
+		countPrefixKeys(w, op, prefix, newPrefix, batchSize, dryRunCheck.Checked)
+	}, w)
+	form.Resize(fyne.NewSize(windowWidth/2, 0))
+	form.Show()
+}
+
+// prefixOperationText 描述操作，例如 `Move keys under "a:" to "b:"`
+func prefixOperationText(op store.PrefixOperation, prefix, newPrefix []byte) string {
+	text := fmt.Sprintf("%s keys under %q", op, keyCodec.Format(prefix))
+	if op != store.PrefixDelete {
+		text += fmt.Sprintf(" to %q", keyCodec.Format(newPrefix))
+	}
+	return text
+}
+
+// countPrefixKeys 在后台统计前缀下的键数，完成后列出受影响的键或确认执行
+func countPrefixKeys(w fyne.Window, op store.PrefixOperation, prefix, newPrefix []byte, batchSize int, dryRun bool) {
+	opEnv, opDBI := env, dbi
+	target := compareTarget{connection: selectedConnectionIndex, dbiName: selectedDBIName}
+	ctx, cancel := context.WithCancel(context.Background())
+	countDialog := dialog.NewCustom("Prefix Operation", "Cancel", widget.NewLabel("Counting keys..."), w)
+	countDialog.SetOnClosed(cancel)
+	countDialog.Show()
+
+	go func() {
+		count, err := store.CountKeys(ctx, opEnv, opDBI, store.KeyRange{Prefix: prefix}, true)
+		countDialog.Hide()
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if err != nil {
+			showErrorLog("Error counting keys: " + err.Error())
+			return
+		}
+		if count == 0 {
+			showInfoLog("No keys under the prefix")
+			return
+		}
+		if dryRun {
+			showPrefixDryRun(w, opEnv, opDBI, op, prefix, newPrefix, count)
+			return
+		}
+		message := fmt.Sprintf("%s?\n%d keys are affected, committed every %d keys. If an error occurs, the current batch is rolled back, earlier batches stay committed.\nEach committed batch is added to the history and can be undone.",
+			prefixOperationText(op, prefix, newPrefix), count, batchSize)
+		dialog.ShowConfirm("Prefix Operation", message, func(ok bool) {
+			if ok {
+				applyPrefixOperation(w, opEnv, opDBI, target, op, prefix, newPrefix, batchSize, count)
+			}
+		}, w)
+	}()
+}
+
+// showPrefixDryRun 列出前 prefixDryRunKeys 个受影响的键及其目标键，不写入数据库
+func showPrefixDryRun(w fyne.Window, opEnv *lmdb.Env, opDBI lmdb.DBI, op store.PrefixOperation, prefix, newPrefix []byte, count int) {
+	keys, err := store.ListPrefixKeys(opEnv, opDBI, prefix, prefixDryRunKeys)
+	if err != nil {
+		showErrorLog("Error listing keys: " + err.Error())
+		return
+	}
+	lines := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		line := keyCodec.Format(key)
+		if op != store.PrefixDelete {
+			line += " → " + keyCodec.Format(store.ReplacePrefix(key, prefix, newPrefix))
+		}
+		lines = append(lines, line)
+	}
+	if count > len(keys) {
+		lines = append(lines, fmt.Sprintf("... and %d more", count-len(keys)))
+	}
+	keysEntry := widget.NewMultiLineEntry()
+	keysEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	keysEntry.SetText(strings.Join(lines, "\n"))
+	keysEntry.Disable()
+	content := container.NewBorder(widget.NewLabel(fmt.Sprintf("Dry run: %s would affect %d keys", prefixOperationText(op, prefix, newPrefix), count)),
+		nil, nil, nil, keysEntry)
+	d := dialog.NewCustom("Prefix Operation", "Close", content, w)
+	d.Resize(fyne.NewSize(windowWidth/2, windowHeight/2))
+	d.Show()
+}
+
+// applyPrefixOperation 在后台分批执行前缀操作，并在对话框中显示进度。每个已提交的批次加入 target 所在连接的历史
+func applyPrefixOperation(w fyne.Window, opEnv *lmdb.Env, opDBI lmdb.DBI, target compareTarget, op store.PrefixOperation, prefix, newPrefix []byte, batchSize, count int) {
+	progressBar := widget.NewProgressBar()
+	progressLabel := widget.NewLabel(fmt.Sprintf("0 / %d keys", count))
+	ctx, cancel := context.WithCancel(context.Background())
+	progressDialog := dialog.NewCustom("Prefix Operation", "Cancel", widget.NewForm(
+		widget.NewFormItem("Operation", widget.NewLabel(prefixOperationText(op, prefix, newPrefix))),
+		widget.NewFormItem("Progress", progressBar),
+		widget.NewFormItem("", progressLabel),
+	), w)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Show()
+
+	go func() {
+		done, err := store.ApplyPrefixOperation(ctx, opEnv, opDBI, op, prefix, newPrefix, batchSize, func(keys int, changes []store.Change) {
+			queueUIEvent(func() {
+				recordConnectionHistory(target.connection, target.dbiName, changes)
+			})
+			progressBar.SetValue(float64(keys) / float64(count))
+			progressLabel.SetText(fmt.Sprintf("%d / %d keys", keys, count))
+		})
+		progressDialog.Hide()
+
+		text := fmt.Sprintf("%s: %d keys", prefixOperationText(op, prefix, newPrefix), done)
+		var exists *store.KeyExistsError
+		switch {
+		case errors.Is(err, context.Canceled):
+			showInfoLog(text + " (cancelled)")
+		case errors.As(err, &exists):
+			showErrorLog(text + ", stopped because " + keyCodec.Format(exists.Key) + " already exists")
+		case err != nil:
+			showErrorLog(text + ", stopped, the current batch was rolled back: " + err.Error())
+		default:
+			showInfoLog(text)
+		}
+		if done > 0 {
+			queueReloadKeyValues(opEnv, opDBI)
+		}
+	}()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+	"github.com/zshimonz/lmdb-gui-client/config"
+)
+
+var protoSchema *codec.ProtoSchema
+var protoMappings []config.ProtoMapping
+
+// loadProtoSchema 加载连接配置的 protobuf schema，未配置时清空
+func loadProtoSchema(connection config.ConnectionConfig) {
+	protoSchema = nil
+	protoMappings = connection.ProtoMappings
+	if len(connection.ProtoFiles) == 0 && len(connection.DescriptorSets) == 0 {
+		return
+	}
+	schema, err := codec.LoadProtoSchema(connection.ProtoFiles, connection.ProtoImportPaths, connection.DescriptorSets)
+	if err != nil {
+		showErrorLog("Error loading protobuf schema: " + err.Error())
+		return
+	}
+	protoSchema = schema
+}
+
+// protoDecoderForKey 返回与键前缀最长匹配的消息类型的解码器，没有匹配时返回 nil
+func protoDecoderForKey(key string) codec.ValueDecoder {
+	if protoSchema == nil {
+		return nil
+	}
+	message := ""
+	matched := -1
+	for _, mapping := range protoMappings {
+		if strings.HasPrefix(key, mapping.KeyPrefix) && len(mapping.KeyPrefix) > matched {
+			message = mapping.Message
+			matched = len(mapping.KeyPrefix)
+		}
+	}
+	if matched < 0 {
+		return nil
+	}
+	decoder, err := protoSchema.Decoder(message)
+	if err != nil {
+		showErrorLog(err.Error())
+		return nil
+	}
+	return decoder
+}
+
+// protoSettingsEntries 是连接表单中 protobuf 相关的输入框
+type protoSettingsEntries struct {
+	protoFiles     *widget.Entry
+	importPaths    *widget.Entry
+	descriptorSets *widget.Entry
+	mappings       *widget.Entry
+}
+
+func newProtoSettingsEntries() *protoSettingsEntries {
+	e := &protoSettingsEntries{
+		protoFiles:     widget.NewEntry(),
+		importPaths:    widget.NewEntry(),
+		descriptorSets: widget.NewEntry(),
+		mappings:       widget.NewMultiLineEntry(),
+	}
+	e.protoFiles.SetPlaceHolder("Comma separated .proto files (optional)")
+	e.importPaths.SetPlaceHolder("Comma separated import paths (optional)")
+	e.descriptorSets.SetPlaceHolder("Comma separated FileDescriptorSet files (optional)")
+	e.mappings.SetPlaceHolder("One mapping per line: key_prefix=package.Message")
+	e.mappings.SetMinRowsVisible(3)
+	return e
+}
+
+func (e *protoSettingsEntries) rows() []fyne.CanvasObject {
+	newLabel := func(text string) *widget.Label {
+		label := widget.NewLabel(text)
+		label.TextStyle = fyne.TextStyle{Monospace: true}
+		return label
+	}
+	return []fyne.CanvasObject{
+		container.NewBorder(nil, nil, newLabel("Proto  Files   :"), nil, e.protoFiles),
+		container.NewBorder(nil, nil, newLabel("Import  Paths  :"), nil, e.importPaths),
+		container.NewBorder(nil, nil, newLabel("Descriptor Sets:"), nil, e.descriptorSets),
+		container.NewBorder(nil, nil, newLabel("Proto Mappings :"), nil, e.mappings),
+	}
+}
+
+func (e *protoSettingsEntries) setConnection(connection config.ConnectionConfig) {
+	e.protoFiles.SetText(strings.Join(connection.ProtoFiles, ", "))
+	e.importPaths.SetText(strings.Join(connection.ProtoImportPaths, ", "))
+	e.descriptorSets.SetText(strings.Join(connection.DescriptorSets, ", "))
+	e.mappings.SetText(formatProtoMappings(connection.ProtoMappings))
+}
+
+func (e *protoSettingsEntries) reset() {
+	e.setConnection(config.ConnectionConfig{})
+}
+
+// applyTo 校验输入并写入连接配置，配置了 schema 时会尝试加载以尽早发现错误
+func (e *protoSettingsEntries) applyTo(connection *config.ConnectionConfig) error {
+	mappings, err := parseProtoMappings(e.mappings.Text)
+	if err != nil {
+		return err
+	}
+	protoFiles := splitList(e.protoFiles.Text)
+	importPaths := splitList(e.importPaths.Text)
+	descriptorSets := splitList(e.descriptorSets.Text)
+	if len(protoFiles) > 0 || len(descriptorSets) > 0 {
+		schema, err := codec.LoadProtoSchema(protoFiles, importPaths, descriptorSets)
+		if err != nil {
+			return err
+		}
+		for _, mapping := range mappings {
+			if _, err := schema.Decoder(mapping.Message); err != nil {
+				return err
+			}
+		}
+	} else if len(mappings) > 0 {
+		return errors.New("proto mappings require proto files or descriptor sets")
+	}
+	connection.ProtoFiles = protoFiles
+	connection.ProtoImportPaths = importPaths
+	connection.DescriptorSets = descriptorSets
+	connection.ProtoMappings = mappings
+	return nil
+}
+
+// splitList 解析逗号分隔的列表，忽略空白项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseProtoMappings 解析每行一个的 "键前缀=消息类型" 映射
+func parseProtoMappings(text string) ([]config.ProtoMapping, error) {
+	var mappings []config.ProtoMapping
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i := strings.LastIndex(line, "=")
+		if i < 0 {
+			return nil, errors.New("invalid proto mapping: " + line)
+		}
+		message := strings.TrimSpace(line[i+1:])
+		if message == "" {
+			return nil, errors.New("invalid proto mapping: " + line)
+		}
+		mappings = append(mappings, config.ProtoMapping{KeyPrefix: line[:i], Message: message})
+	}
+	return mappings, nil
+}
+
+func formatProtoMappings(mappings []config.ProtoMapping) string {
+	lines := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		lines[i] = mapping.KeyPrefix + "=" + mapping.Message
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+var readersTabItem *fyne.Container
+var readers []store.Reader
+var readersLastTxnID int64
+var readersTable *widget.Table
+var readersSummaryLabel *widget.Label
+
+var readerColumns = []string{"PID", "Thread", "Txn ID", "Lag", "Status"}
+
+func initReadersTabItem() *fyne.Container {
+	readersSummaryLabel = widget.NewLabel("")
+	readersSummaryLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	readersTable = widget.NewTableWithHeaders(
+		func() (int, int) {
+			return len(readers), len(readerColumns)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Alignment = fyne.TextAlignCenter
+			return label
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			reader := readers[id.Row]
+			// 进程已不存在的读者显示为红色
+			if reader.Alive {
+				label.Importance = widget.MediumImportance
+			} else {
+				label.Importance = widget.DangerImportance
+			}
+			switch id.Col {
+			case 0:
+				label.SetText(strconv.Itoa(reader.PID))
+			case 1:
+				label.SetText(reader.Thread)
+			case 2:
+				if reader.TxnID < 0 {
+					label.SetText("-")
+				} else {
+					label.SetText(strconv.FormatInt(reader.TxnID, 10))
+				}
+			case 3:
+				// 读事务落后最新事务的数量，长时间不结束的读事务会阻止空闲页被回收
+				if reader.TxnID < 0 {
+					label.SetText("-")
+				} else {
+					label.SetText(strconv.FormatInt(readersLastTxnID-reader.TxnID, 10))
+				}
+			default:
+				switch {
+				case reader.Own:
+					label.SetText("this process")
+				case reader.Alive:
+					label.SetText("alive")
+				default:
+					label.SetText("STALE")
+				}
+			}
+		},
+	)
+	readersTable.ShowHeaderColumn = false
+	readersTable.UpdateHeader = func(id widget.TableCellID, template fyne.CanvasObject) {
+		template.(*widget.Label).SetText(readerColumns[id.Col])
+	}
+	for i := range readerColumns {
+		readersTable.SetColumnWidth(i, oneCharWidth*16)
+	}
+
+	refreshButton := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
+		refreshReaders()
+	})
+	checkButton := widget.NewButtonWithIcon("Clear Stale Readers", theme.DeleteIcon(), func() {
+		cleared, err := store.ClearStaleReaders(env)
+		if err != nil {
+			showErrorLog("Error checking readers: " + err.Error())
+			return
+		}
+		showInfoLog(fmt.Sprintf("Cleared %d stale reader slots", cleared))
+		refreshReaders()
+	})
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		showStatsTabItem()
+	})
+
+	border := container.NewBorder(readersSummaryLabel, container.NewGridWithColumns(3, refreshButton, checkButton, backButton), nil, nil, readersTable)
+	border.Hide()
+	return border
+}
+
+func showReadersTabItem() {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	err := tabTitle.Set("Readers")
+	if err != nil {
+		return
+	}
+	refreshReaders()
+	readersTabItem.Show()
+	statsTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+// refreshReaders 重新读取锁文件中的读者表
+func refreshReaders() {
+	list, err := store.ListReaders(env)
+	if err != nil {
+		showErrorLog("Error listing readers: " + err.Error())
+		return
+	}
+	info, err := env.Info()
+	if err != nil {
+		showErrorLog("Error reading LMDB environment info: " + err.Error())
+		return
+	}
+	readers, readersLastTxnID = list, info.LastTxnID
+	stale := 0
+	for _, reader := range readers {
+		if !reader.Alive {
+			stale++
+		}
+	}
+	readersSummaryLabel.SetText(fmt.Sprintf("Readers: %d / %d    Stale: %d    Last Txn ID: %d", len(readers), info.MaxReaders, stale, readersLastTxnID))
+	readersTable.Refresh()
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/watch"
+)
+
+// 默认的自动刷新间隔（秒）
+const defaultRefreshInterval = 5
+
+// 自动刷新间隔的选项（秒）
+var refreshIntervals = []int{1, 2, 5, 10, 30, 60}
+
+var autoRefreshCheck *widget.Check
+
+// refreshGeneration 每次开始或停止自动刷新时递增，旧的监听发现编号变化后不再刷新表格
+var refreshGeneration atomic.Int64
+var refreshCancel context.CancelFunc
+
+// refreshDone 在监听协程结束后关闭，关闭环境前需要等待
+var refreshDone chan struct{}
+
+// changedKeys 为最近一次自动刷新时当前页中新增或值变化的键，在表格中高亮显示，由 keyValuesLock 保护
+var changedKeys map[string]bool
+
+// selectedKeyCell 为表格中选中的单元格，自动刷新后按键重新选中
+var selectedKeyCell widget.TableCellID
+
+// initAutoRefresh 创建自动刷新复选框和刷新间隔下拉框
+func initAutoRefresh() (*widget.Check, *widget.Select) {
+	autoRefreshCheck = widget.NewCheck("Auto Refresh", func(b bool) {
+		if b {
+			startAutoRefresh()
+			return
+		}
+		stopAutoRefresh()
+		setChangedKeys(nil)
+		keyValueTable.Refresh()
+	})
+
+	options := make([]string, len(refreshIntervals))
+	for i, seconds := range refreshIntervals {
+		options[i] = strconv.Itoa(seconds) + "s"
+	}
+	intervalSelect := widget.NewSelect(options, func(s string) {
+		seconds, err := strconv.Atoi(strings.TrimSuffix(s, "s"))
+		if err != nil || seconds == refreshIntervalSeconds() {
+			return
+		}
+		config.Config.RefreshInterval = seconds
+		if err := config.SaveConfig(); err != nil {
+			showErrorLog("Error saving config: " + err.Error())
+		}
+		if autoRefreshCheck.Checked {
+			startAutoRefresh()
+		}
+	})
+	intervalSelect.Selected = strconv.Itoa(refreshIntervalSeconds()) + "s"
+	return autoRefreshCheck, intervalSelect
+}
+
+// refreshIntervalSeconds 返回配置的自动刷新间隔
+func refreshIntervalSeconds() int {
+	if config.Config.RefreshInterval <= 0 {
+		return defaultRefreshInterval
+	}
+	return config.Config.RefreshInterval
+}
+
+// stopAutoRefresh 停止监听数据库的变化，并等待监听协程结束
+func stopAutoRefresh() {
+	refreshGeneration.Add(1)
+	if refreshCancel != nil {
+		refreshCancel()
+		refreshCancel = nil
+	}
+	if refreshDone != nil {
+		<-refreshDone
+		refreshDone = nil
+	}
+}
+
+// startAutoRefresh 在后台监听当前连接的数据库，有新的提交时刷新当前页。
+// 按间隔检查 LastTxnID，数据文件变化时立即检查，数据库没有变化时不会重新加载。
+// 监听协程只通知有新的提交，重新加载放入界面事件队列中执行，与翻页等操作不会同时进行，
+// 停止监听之后已放入队列的刷新发现编号变化后跳过，不会读取已关闭的环境
+func startAutoRefresh() {
+	stopAutoRefresh()
+	if env == nil || selectedConnectionIndex == -1 || !autoRefreshCheck.Checked {
+		return
+	}
+	generation := refreshGeneration.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	refreshCancel, refreshDone = cancel, done
+
+	watchEnv := env
+	databasePath := config.Config.Connections[selectedConnectionIndex].DatabasePath
+	interval := time.Duration(refreshIntervalSeconds()) * time.Second
+	go func() {
+		defer close(done)
+		err := watch.Watch(ctx, watchEnv, databasePath, interval, func(int64) {
+			queueUIEvent(func() {
+				if refreshGeneration.Load() == generation {
+					autoRefreshKeyValues()
+				}
+			})
+		})
+		if refreshGeneration.Load() != generation || errors.Is(err, context.Canceled) {
+			return
+		}
+		showErrorLog("Auto refresh stopped: " + err.Error())
+	}()
+}
+
+// autoRefreshKeyValues 重新加载当前页，保留页码和选中的键，新增或值变化的行会被高亮
+func autoRefreshKeyValues() {
+	// 表格中显示的是值搜索的结果时不刷新，避免覆盖搜索结果
+	if searchResultsShown {
+		return
+	}
+	rows := currentKeyValues()
+	before := make(map[string]KeyValue, len(rows))
+	for _, kv := range rows {
+		before[string(kv.RawKey)] = kv
+	}
+
+	totalRecordsCached = false
+	reloadKeyValues()
+
+	changed := make(map[string]bool)
+	for _, kv := range currentKeyValues() {
+		old, ok := before[string(kv.RawKey)]
+		if !ok || old.ValueHash != kv.ValueHash || old.Count != kv.Count {
+			changed[string(kv.RawKey)] = true
+		}
+	}
+	setChangedKeys(changed)
+	keyValueTable.Refresh()
+	restoreSelectedKey()
+	// 本程序自己的写入已经重新加载过，刷新后没有变化时不提示
+	if len(changed) > 0 {
+		showInfoLog(fmt.Sprintf("Auto refreshed: %d changed rows on this page", len(changed)))
+	}
+}
+
+// restoreSelectedKey 刷新后按键重新选中表格中的行。重新选中时不触发选中事件，
+// 值编辑框中未保存的修改会被保留，没有修改时重新读取变化了的值
+func restoreSelectedKey() {
+	if selectedKey == "" {
+		return
+	}
+	row := -1
+	for i, kv := range currentKeyValues() {
+		if string(kv.RawKey) == selectedKey {
+			row = i
+			break
+		}
+	}
+
+	onSelected, onUnselected := keyValueTable.OnSelected, keyValueTable.OnUnselected
+	keyValueTable.OnSelected, keyValueTable.OnUnselected = nil, nil
+	if row >= 0 {
+		selectedKeyCell.Row = row
+		keyValueTable.Select(selectedKeyCell)
+	} else {
+		// 选中的键已被删除或移出当前页，值面板仍保留该键
+		keyValueTable.UnselectAll()
+	}
+	keyValueTable.OnSelected, keyValueTable.OnUnselected = onSelected, onUnselected
+
+	if row >= 0 && keyChanged(selectedKey) && !valueModified() {
+		refreshValueView(valueView)
+	}
+}
+
+// setChangedKeys 设置高亮显示的键，调用者随后需要刷新表格
+func setChangedKeys(keys map[string]bool) {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	changedKeys = keys
+}
+
+// keyChanged 表示键在最近一次自动刷新时新增或值发生了变化
+func keyChanged(key string) bool {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	return changedKeys[key]
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/search"
+)
+
+// 搜索结果的最大数量，达到后停止扫描
+const maxSearchResults = 10000
+
+// 扫描过程中刷新表格的间隔
+const searchRefreshInterval = 200 * time.Millisecond
+
+// searchGeneration 每次开始或停止搜索时递增，旧的搜索发现编号变化后不再写入结果
+var searchGeneration atomic.Int64
+var searchCancel context.CancelFunc
+
+// searchDone 在搜索协程结束读事务后关闭，关闭环境前需要等待
+var searchDone chan struct{}
+
+// searchResultsShown 表示表格中显示的是搜索结果，重新加载分页时清除
+var searchResultsShown bool
+
+func initSearchBar() *fyne.Container {
+	modeSelect := widget.NewSelect(search.Modes(), nil)
+	modeSelect.SetSelected(string(search.ModeSubstring))
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder("Search values in the key filter")
+	modeSelect.OnChanged = func(s string) {
+		switch search.Mode(s) {
+		case search.ModeRegex:
+			searchEntry.SetPlaceHolder(`Regular expression, e.g. "order_id":\s*123\b`)
+		case search.ModeJSONPath:
+			searchEntry.SetPlaceHolder(`JSON path, e.g. $.order_id == 123 or $.tags contains "vip"`)
+		default:
+			searchEntry.SetPlaceHolder("Search values in the key filter")
+		}
+	}
+
+	searchButton := widget.NewButtonWithIcon("Search", theme.SearchIcon(), func() {
+		startSearch(search.Mode(modeSelect.Selected), searchEntry.Text)
+	})
+	searchEntry.OnSubmitted = func(s string) {
+		startSearch(search.Mode(modeSelect.Selected), s)
+	}
+	stopButton := widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), func() {
+		if searchCancel != nil {
+			searchCancel()
+		}
+	})
+
+	searchLabel := widget.NewLabel("Value Search:")
+	return container.NewBorder(nil, nil, container.NewHBox(widget.NewIcon(theme.SearchIcon()), searchLabel, modeSelect),
+		container.NewHBox(searchButton, stopButton), searchEntry)
+}
+
+// stopSearch 停止正在进行的搜索，并等待搜索协程结束读事务，之后搜索协程不会再修改表格
+func stopSearch() {
+	searchGeneration.Add(1)
+	if searchCancel != nil {
+		searchCancel()
+		searchCancel = nil
+	}
+	if searchDone != nil {
+		<-searchDone
+		searchDone = nil
+	}
+}
+
+// startSearch 在后台扫描当前前缀和键范围内的值，匹配的记录会陆续显示在表格中
+func startSearch(mode search.Mode, pattern string) {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	matcher, err := search.NewMatcher(mode, pattern)
+	if err != nil {
+		showErrorLog("Invalid search: " + err.Error())
+		return
+	}
+	prefixText, err := keyPrefix.Get()
+	if err != nil {
+		return
+	}
+	keyRange, err := currentKeyRange(prefixText)
+	if err != nil {
+		showErrorLog(err.Error())
+		return
+	}
+
+	stopSearch()
+	generation := searchGeneration.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	searchCancel, searchDone = cancel, done
+
+	keyValueTable.UnselectAll()
+	searchResultsShown = true
+	setChangedKeys(nil)
+	setKeyValues(make([]KeyValue, 0))
+	keyValueTable.Refresh()
+	pageLabel.SetText("Searching...")
+	recordCountLabel.SetText("Matches: 0")
+
+	searchEnv, searchDBI, dupSort := env, dbi, dbiIsDupSort
+	go func() {
+		lastRefresh := time.Now()
+		var lastKey []byte
+		// 匹配的记录先收集在协程内，定期替换表格的内容
+		results := make([]KeyValue, 0)
+		scanned, err := search.Scan(ctx, searchEnv, searchDBI, keyRange, matcher, func(key, val []byte) bool {
+			if searchGeneration.Load() != generation {
+				return false
+			}
+			// DupSort 数据库中同一个键的多个重复值匹配时只显示一次
+			if dupSort && lastKey != nil && bytes.Equal(key, lastKey) {
+				return true
+			}
+			lastKey = append(lastKey[:0], key...)
+			results = append(results, newKeyValue(key, val, prefixText))
+			if time.Since(lastRefresh) > searchRefreshInterval {
+				lastRefresh = time.Now()
+				if !publishSearchResults(generation, results) {
+					return false
+				}
+				recordCountLabel.SetText(fmt.Sprintf("Matches: %d", len(results)))
+				keyValueTable.Refresh()
+			}
+			return len(results) < maxSearchResults
+		})
+		close(done)
+		if !publishSearchResults(generation, results) {
+			return
+		}
+		cancel()
+
+		matches := len(results)
+		recordCountLabel.SetText(fmt.Sprintf("Matches: %d", matches))
+		pageLabel.SetText("Search Results")
+		keyValueTable.Refresh()
+		adaptiveColumnWidths()
+		switch {
+		case errors.Is(err, context.Canceled):
+			showInfoLog(fmt.Sprintf("Search stopped: %d matches in %d records", matches, scanned))
+		case err != nil:
+			showErrorLog("Error searching: " + err.Error())
+		case matches >= maxSearchResults:
+			showInfoLog(fmt.Sprintf("Search stopped at %d matches", maxSearchResults))
+		default:
+			showInfoLog(fmt.Sprintf("Search finished: %d matches in %d records", matches, scanned))
+		}
+	}()
+}
+
+// publishSearchResults 将搜索结果显示到表格中，搜索已停止或被新的搜索取代时返回 false。
+// 在持有 keyValuesLock 时检查编号，避免在重新加载分页之后写入旧的结果
+func publishSearchResults(generation int64, results []KeyValue) bool {
+	keyValuesLock.Lock()
+	defer keyValuesLock.Unlock()
+	if searchGeneration.Load() != generation {
+		return false
+	}
+	// 协程之后继续追加结果，表格只使用当前长度的部分
+	keyValues = results[:len(results):len(results)]
+	return true
+}
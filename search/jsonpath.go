@@ -0,0 +1,236 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type jsonOp int
+
+const (
+	opExists jsonOp = iota
+	opEquals
+	opContains
+)
+
+// pathStep 为 JSON 路径中的一级：对象字段、数组下标或 [*] 通配
+type pathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// JSONQuery 为 JSON 路径条件，只匹配 JSON 格式的值
+type JSONQuery struct {
+	path     []pathStep
+	op       jsonOp
+	expected interface{}
+}
+
+// ParseJSONQuery 解析 JSON 路径条件，格式为 "路径 [== 值 | contains 值]"，例如：
+//
+//	$.order_id == 123
+//	$.customer.name == "Alice"
+//	$.items[*].sku contains "ABC"
+//	$.tags contains vip
+//	$.deleted_at
+//
+// 路径以可选的 "$" 开头，由 ".字段"、"[下标]" 和 "[*]" 组成；值可以是任意 JSON，不是合法 JSON 时按字符串处理。
+// 只有路径时匹配存在该路径的值；== 匹配相等的值；contains 对字符串匹配子串，对数组匹配包含该元素，对对象匹配包含该字段
+func ParseJSONQuery(query string) (*JSONQuery, error) {
+	query = strings.TrimSpace(query)
+	pathEnd := strings.IndexAny(query, " \t=")
+	if pathEnd < 0 {
+		pathEnd = len(query)
+	}
+	path, err := parsePath(query[:pathEnd])
+	if err != nil {
+		return nil, err
+	}
+	q := &JSONQuery{path: path, op: opExists}
+	rest := strings.TrimSpace(query[pathEnd:])
+	var value string
+	switch {
+	case rest == "":
+		return q, nil
+	case strings.HasPrefix(rest, "=="):
+		q.op, value = opEquals, rest[2:]
+	case strings.HasPrefix(rest, "contains"):
+		q.op, value = opContains, rest[len("contains"):]
+	default:
+		return nil, fmt.Errorf("expected == or contains after path, got %q", rest)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("missing value after operator")
+	}
+	if q.expected, err = decodeJSON([]byte(value)); err != nil {
+		q.expected = value
+	}
+	return q, nil
+}
+
+func parsePath(path string) ([]pathStep, error) {
+	path = strings.TrimPrefix(path, "$")
+	steps := make([]pathStep, 0)
+	for path != "" {
+		switch path[0] {
+		case '.':
+			end := strings.IndexAny(path[1:], ".[")
+			if end < 0 {
+				end = len(path) - 1
+			}
+			field := path[1 : end+1]
+			if field == "" {
+				return nil, fmt.Errorf("empty field name in path")
+			}
+			if field == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{field: field})
+			}
+			path = path[end+1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in path")
+			}
+			inner := path[1:end]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case strings.HasPrefix(inner, "\""):
+				field, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field %s in path", inner)
+				}
+				steps = append(steps, pathStep{field: field})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index [%s] in path", inner)
+				}
+				steps = append(steps, pathStep{index: index, isIndex: true})
+			}
+			path = path[end+1:]
+		default:
+			// 允许省略开头的 "."，例如 "order_id == 1"
+			if len(steps) == 0 {
+				path = "." + path
+				continue
+			}
+			return nil, fmt.Errorf("unexpected %q in path", path)
+		}
+	}
+	return steps, nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("trailing data")
+	}
+	return v, nil
+}
+
+// Match 实现 Matcher
+func (q *JSONQuery) Match(val []byte) bool {
+	trimmed := bytes.TrimSpace(val)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	doc, err := decodeJSON(trimmed)
+	if err != nil {
+		return false
+	}
+	for _, v := range q.eval(doc) {
+		switch q.op {
+		case opExists:
+			return true
+		case opEquals:
+			if jsonEqual(v, q.expected) {
+				return true
+			}
+		case opContains:
+			if jsonContains(v, q.expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// eval 返回路径匹配到的所有值，通配会展开为多个值
+func (q *JSONQuery) eval(doc interface{}) []interface{} {
+	values := []interface{}{doc}
+	for _, step := range q.path {
+		next := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			switch node := v.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range node {
+						next = append(next, child)
+					}
+				} else if child, ok := node[step.field]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, node...)
+				} else if step.isIndex && step.index < len(node) {
+					next = append(next, node[step.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+func jsonEqual(a, b interface{}) bool {
+	na, okA := a.(json.Number)
+	nb, okB := b.(json.Number)
+	if okA && okB {
+		if na == nb {
+			return true
+		}
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func jsonContains(v, expected interface{}) bool {
+	switch node := v.(type) {
+	case string:
+		text, ok := expected.(string)
+		if !ok {
+			text = fmt.Sprint(expected)
+		}
+		return strings.Contains(node, text)
+	case []interface{}:
+		for _, element := range node {
+			if jsonEqual(element, expected) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		if field, ok := expected.(string); ok {
+			_, exists := node[field]
+			return exists
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Package search 实现按值搜索：子串、正则表达式以及 JSON 路径条件
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// Mode 为搜索方式
+type Mode string
+
+const (
+	ModeSubstring Mode = "Substring"
+	ModeRegex     Mode = "Regex"
+	ModeJSONPath  Mode = "JSON Path"
+)
+
+// Modes 返回所有搜索方式
+func Modes() []string {
+	return []string{string(ModeSubstring), string(ModeRegex), string(ModeJSONPath)}
+}
+
+// Matcher 判断一个值是否匹配搜索条件
+type Matcher interface {
+	Match(val []byte) bool
+}
+
+// NewMatcher 按搜索方式解析搜索条件。JSON Path 的条件格式见 ParseJSONQuery
+func NewMatcher(mode Mode, pattern string) (Matcher, error) {
+	switch mode {
+	case ModeSubstring:
+		if pattern == "" {
+			return nil, fmt.Errorf("empty search text")
+		}
+		return substringMatcher([]byte(pattern)), nil
+	case ModeRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return regexMatcher{re}, nil
+	case ModeJSONPath:
+		return ParseJSONQuery(pattern)
+	}
+	return nil, fmt.Errorf("unknown search mode %q", string(mode))
+}
+
+type substringMatcher []byte
+
+func (m substringMatcher) Match(val []byte) bool {
+	return bytes.Contains(val, m)
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) Match(val []byte) bool {
+	return m.re.Match(val)
+}
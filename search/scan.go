@@ -0,0 +1,40 @@
+package search
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// checkInterval 为两次检查 ctx 是否取消之间扫描的记录数
+const checkInterval = 1000
+
+var errStop = errors.New("stop scan")
+
+// Scan 在一个读事务中按 keyRange 遍历 dbi 中的键值对，对匹配 matcher 的记录调用 onMatch。
+// onMatch 收到的 key 和 val 只在本次调用中有效，返回 false 时停止扫描；ctx 被取消时返回 ctx.Err()。
+// 返回已扫描的记录数
+func Scan(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, keyRange store.KeyRange, matcher Matcher, onMatch func(key, val []byte) bool) (int, error) {
+	scanned := 0
+	err := env.View(func(txn *lmdb.Txn) error {
+		return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+			scanned++
+			if scanned%checkInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if matcher.Match(val) && !onMatch(key, val) {
+				return errStop
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+	return scanned, err
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/history"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// connectionSession 为一个连接标签页的状态。每个打开的连接有自己的环境，
+// 当前标签页的状态保存在全局变量中，切换标签页时保存到会话中并恢复目标标签页的状态
+type connectionSession struct {
+	tab             *container.TabItem
+	connectionIndex int
+	dbiName         string
+
+	env           *lmdb.Env
+	dbi           lmdb.DBI
+	dbiIsDupSort  bool
+	readOnly      bool
+	keyCodec      *codec.KeyCodec
+	protoSchema   *codec.ProtoSchema
+	protoMappings []config.ProtoMapping
+	writeHistory  *history.History
+
+	prefix, rangeStart, rangeEnd string
+	descending                   bool
+
+	currentPage               int
+	loadedPage                int
+	pageFirstKey, pageLastKey []byte
+
+	selectedKey     string
+	selectedKeyCell widget.TableCellID
+	// valueText 为值编辑框中未保存的修改，valueModified 为 false 时恢复后重新读取值
+	valueText     string
+	valueModified bool
+}
+
+// sessions 按标签页的顺序保存所有打开的连接，activeSession 为当前标签页，没有打开的连接时为 nil
+var sessions []*connectionSession
+var activeSession *connectionSession
+var connectionTabs *container.DocTabs
+
+// initConnectionTabs 创建连接标签栏，标签页只作为切换连接的标签，内容区共用键值列表
+func initConnectionTabs() *container.DocTabs {
+	connectionTabs = container.NewDocTabs()
+	connectionTabs.OnSelected = func(tab *container.TabItem) {
+		session := sessionForTab(tab)
+		if session == nil || session == activeSession {
+			return
+		}
+		saveActiveSession()
+		restoreSession(session)
+	}
+	connectionTabs.CloseIntercept = func(tab *container.TabItem) {
+		if session := sessionForTab(tab); session != nil {
+			closeSession(session)
+		}
+	}
+	connectionTabs.Hide()
+	return connectionTabs
+}
+
+func sessionForTab(tab *container.TabItem) *connectionSession {
+	for _, session := range sessions {
+		if session.tab == tab {
+			return session
+		}
+	}
+	return nil
+}
+
+// findSession 返回连接已打开的标签页，未打开时返回 nil
+func findSession(connectionIndex int) *connectionSession {
+	for _, session := range sessions {
+		if session.connectionIndex == connectionIndex {
+			return session
+		}
+	}
+	return nil
+}
+
+// sessionEnv 返回连接在标签页中已打开的环境，未打开时返回 nil
+func sessionEnv(connectionIndex int) *lmdb.Env {
+	if connectionIndex == selectedConnectionIndex && env != nil {
+		return env
+	}
+	if session := findSession(connectionIndex); session != nil {
+		return session.env
+	}
+	return nil
+}
+
+// sessionHistory 返回连接在标签页中已加载的写操作历史，未打开时返回 nil
+func sessionHistory(connectionIndex int) *history.History {
+	if connectionIndex == selectedConnectionIndex {
+		return writeHistory
+	}
+	if session := findSession(connectionIndex); session != nil {
+		return session.writeHistory
+	}
+	return nil
+}
+
+func sessionTitle(connectionIndex int, dbiName string) string {
+	return config.Config.Connections[connectionIndex].Name + " / " + dbiDisplayName(dbiName)
+}
+
+// selectConnection 打开连接的数据库。连接已在标签页中打开时切换到该标签页，否则新建标签页，
+// 其他标签页的环境保持打开
+func selectConnection(connectionIndex int, dbiName string) {
+	if session := findSession(connectionIndex); session != nil && session != activeSession {
+		connectionTabs.Select(session.tab)
+	}
+	if activeSession != nil && selectedConnectionIndex == connectionIndex && selectedDBIName == dbiName {
+		// 切换标签页时同步连接树的选中项
+		return
+	}
+
+	previous := activeSession
+	isNew := activeSession == nil || selectedConnectionIndex != connectionIndex
+	if isNew {
+		saveActiveSession()
+		activeSession = nil
+		env = nil
+	}
+	if err := keyPrefix.Set(""); err != nil {
+		return
+	}
+	// 不同连接的键编码布局可能不同，切换时清空键范围
+	_ = rangeStart.Set("")
+	_ = rangeEnd.Set("")
+	keyValueTable.UnselectAll()
+	selectedDBIName = dbiName
+	if err := connectToDB(connectionIndex, true); err != nil {
+		if !isNew {
+			closeSession(activeSession)
+			return
+		}
+		if env != nil {
+			_ = env.Close()
+			env = nil
+		}
+		if previous != nil {
+			restoreSession(previous)
+		} else {
+			clearConnection()
+		}
+		return
+	}
+	selectedConnectionIndex = connectionIndex
+
+	if isNew {
+		session := &connectionSession{connectionIndex: connectionIndex}
+		session.tab = container.NewTabItem("", container.NewStack())
+		sessions = append(sessions, session)
+		activeSession = session
+		connectionTabs.Append(session.tab)
+		connectionTabs.Select(session.tab)
+		connectionTabs.Show()
+	}
+	activeSession.tab.Text = sessionTitle(connectionIndex, dbiName)
+	connectionTabs.Refresh()
+	connectionList.OpenBranch(strconv.Itoa(connectionIndex))
+	connectionList.Select(dbiNodeID(connectionIndex, dbiName))
+	connectionList.Refresh()
+	afterConnectionChanged()
+}
+
+// afterConnectionChanged 在当前连接或数据库变化后刷新打开的页面
+func afterConnectionChanged() {
+	if statsTabItem.Visible() {
+		// 统计页打开时切换为新选中数据库的统计信息
+		refreshStats()
+	} else if readersTabItem.Visible() {
+		refreshReaders()
+	} else if pendingTabItem.Visible() {
+		refreshPendingChanges()
+	} else if historyTabItem.Visible() {
+		refreshHistory()
+	} else if compareTabItem.Visible() {
+		// 比较页与选中的连接无关，保持显示
+	} else if transformTabItem.Visible() {
+		refreshTransformTarget()
+	} else {
+		// hide mainValueSplit
+		keyValuesTabItem.Hidden = false
+	}
+}
+
+// saveActiveSession 停止当前标签页的后台任务，并将全局状态保存到会话中
+func saveActiveSession() {
+	session := activeSession
+	if session == nil {
+		return
+	}
+	stopSearch()
+	stopCount()
+	stopAutoRefresh()
+	stopTransform()
+
+	session.connectionIndex, session.dbiName = selectedConnectionIndex, selectedDBIName
+	session.env, session.dbi, session.dbiIsDupSort = env, dbi, dbiIsDupSort
+	session.readOnly = connectionReadOnly
+	session.keyCodec, session.protoSchema, session.protoMappings = keyCodec, protoSchema, protoMappings
+	session.writeHistory = writeHistory
+
+	session.prefix, _ = keyPrefix.Get()
+	session.rangeStart, _ = rangeStart.Get()
+	session.rangeEnd, _ = rangeEnd.Get()
+	session.descending, _ = descendingOrder.Get()
+	session.currentPage, session.loadedPage = currentPage, loadedPage
+	session.pageFirstKey, session.pageLastKey = pageFirstKey, pageLastKey
+
+	session.selectedKey, session.selectedKeyCell = selectedKey, selectedKeyCell
+	session.valueText, session.valueModified = valueView.Text, selectedKey != "" && valueModified()
+}
+
+// restoreSession 将会话的状态恢复到全局变量中，并从上次显示的页重新读取，保留页码和选中的键
+func restoreSession(session *connectionSession) {
+	keyValueTable.UnselectAll()
+	activeSession = session
+	selectedConnectionIndex, selectedDBIName = session.connectionIndex, session.dbiName
+	env, dbi, dbiIsDupSort = session.env, session.dbi, session.dbiIsDupSort
+	setReadOnlyMode(session.readOnly)
+	keyCodec, protoSchema, protoMappings = session.keyCodec, session.protoSchema, session.protoMappings
+	writeHistory = session.writeHistory
+	if dbiIsDupSort {
+		valueContent.Objects = []fyne.CanvasObject{dupValuesSplit}
+	} else {
+		valueContent.Objects = []fyne.CanvasObject{valueView}
+	}
+	valueContent.Refresh()
+
+	_ = keyPrefix.Set(session.prefix)
+	_ = rangeStart.Set(session.rangeStart)
+	_ = rangeEnd.Set(session.rangeEnd)
+	_ = descendingOrder.Set(session.descending)
+	currentPage, loadedPage = session.currentPage, session.loadedPage
+	pageFirstKey, pageLastKey = session.pageFirstKey, session.pageLastKey
+	// 后台期间数据库可能已经变化，重新统计记录数
+	totalRecordsCached = false
+	loadKeyValues(session.prefix, false)
+
+	if session.selectedKey != "" {
+		for i, kv := range currentKeyValues() {
+			if string(kv.RawKey) == session.selectedKey {
+				cell := session.selectedKeyCell
+				cell.Row = i
+				keyValueTable.Select(cell)
+				if session.valueModified {
+					valueView.SetText(session.valueText)
+				}
+				break
+			}
+		}
+	}
+
+	startAutoRefresh()
+	connectionList.Select(dbiNodeID(selectedConnectionIndex, selectedDBIName))
+	connectionTabs.Select(session.tab)
+	afterConnectionChanged()
+}
+
+// closeSession 关闭标签页及其环境，关闭的是当前标签页时切换到相邻的标签页
+func closeSession(session *connectionSession) {
+	releaseCompareEnv(session.connectionIndex)
+	if session == activeSession {
+		stopSearch()
+		stopCount()
+		stopAutoRefresh()
+		stopTransform()
+		keyValueTable.UnselectAll()
+		session.env = env
+		activeSession = nil
+		env = nil
+	}
+	if session.env != nil {
+		if err := session.env.Close(); err != nil {
+			showErrorLog("Error closing LMDB environment: " + err.Error())
+		}
+	}
+	for i, s := range sessions {
+		if s == session {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			break
+		}
+	}
+
+	connectionTabs.Remove(session.tab)
+	if activeSession != nil {
+		return
+	}
+	if next := sessionForTab(connectionTabs.Selected()); next != nil {
+		restoreSession(next)
+	} else {
+		clearConnection()
+	}
+}
+
+// closeConnection 关闭连接的标签页，连接未打开时不做任何操作
+func closeConnection(connectionIndex int) {
+	if session := findSession(connectionIndex); session != nil {
+		closeSession(session)
+	}
+}
+
+// clearConnection 在所有标签页都关闭后清空当前连接的状态
+func clearConnection() {
+	selectedConnectionIndex = -1
+	selectedDBIName = store.RootDBIName
+	writeHistory = nil
+	setReadOnlyMode(false)
+	if statsTabItem.Visible() || readersTabItem.Visible() || pendingTabItem.Visible() || historyTabItem.Visible() || transformTabItem.Visible() {
+		showKeyValesTabItem()
+	}
+	// show mainValueSplit
+	keyValuesTabItem.Hidden = true
+	connectionList.UnselectAll()
+	connectionTabs.Hide()
+}
+
+// shiftSessions 在删除连接后更新标签页中的连接下标
+func shiftSessions(deletedIndex int) {
+	if selectedConnectionIndex > deletedIndex {
+		selectedConnectionIndex--
+	}
+	for _, session := range sessions {
+		if session.connectionIndex > deletedIndex {
+			session.connectionIndex--
+		}
+		if session != activeSession {
+			session.tab.Text = sessionTitle(session.connectionIndex, session.dbiName)
+		}
+	}
+	if activeSession != nil {
+		activeSession.tab.Text = sessionTitle(selectedConnectionIndex, selectedDBIName)
+		connectionList.Select(dbiNodeID(selectedConnectionIndex, selectedDBIName))
+	}
+	connectionTabs.Refresh()
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 差异视图中每个键最多显示的值数量
+const maxDiffValues = 50
+
+// stagingMode 为 true 时写操作只加入待提交列表，点击 Commit 后在一个写事务中提交
+var stagingMode = binding.NewBool()
+
+// stagedChanges 按数据库节点 ID（见 dbiNodeID）保存待提交的修改
+var stagedChanges = make(map[widget.TreeNodeID][]store.Change)
+
+var pendingTabItem *fyne.Container
+var pendingList *widget.List
+var pendingSummaryLabel *widget.Label
+var pendingBeforeEntry *widget.Entry
+var pendingAfterEntry *widget.Entry
+var pendingButton *widget.Button
+var selectedPendingIndex = -1
+
+// initStagingControls 创建标题栏中的暂存开关、待提交修改按钮和历史记录按钮
+func initStagingControls() *fyne.Container {
+	stagingCheck := widget.NewCheckWithData("Stage Edits", stagingMode)
+	pendingButton = widget.NewButtonWithIcon("Pending (0)", theme.ListIcon(), func() {
+		showPendingTabItem()
+	})
+	historyButton := widget.NewButtonWithIcon("History", theme.HistoryIcon(), func() {
+		showHistoryTabItem()
+	})
+	return container.NewHBox(stagingCheck, pendingButton, historyButton)
+}
+
+func initPendingTabItem(w fyne.Window) *fyne.Container {
+	pendingSummaryLabel = widget.NewLabel("")
+	pendingSummaryLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	pendingList = widget.NewList(
+		func() int { return len(currentStagedChanges()) },
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			changes := currentStagedChanges()
+			if i >= len(changes) {
+				return
+			}
+			o.(*widget.Label).SetText(fmt.Sprintf("%-12s %s", changes[i].Kind, keyCodec.Format(changes[i].Key)))
+		},
+	)
+	pendingBeforeEntry = widget.NewMultiLineEntry()
+	pendingBeforeEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	pendingBeforeEntry.Disable()
+	pendingAfterEntry = widget.NewMultiLineEntry()
+	pendingAfterEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	pendingAfterEntry.Disable()
+	pendingList.OnSelected = func(id widget.ListItemID) {
+		changes := currentStagedChanges()
+		if id < 0 || id >= len(changes) {
+			return
+		}
+		selectedPendingIndex = id
+		pendingBeforeEntry.SetText(formatDiffValues(changes[id].Before))
+		pendingAfterEntry.SetText(formatDiffValues(stagedAfter(changes[id])))
+	}
+	pendingList.OnUnselected = func(id widget.ListItemID) {
+		selectedPendingIndex = -1
+		pendingBeforeEntry.SetText("")
+		pendingAfterEntry.SetText("")
+	}
+
+	commitButton := widget.NewButtonWithIcon("Commit", theme.ConfirmIcon(), func() {
+		commitStagedChanges()
+	})
+	writeButtons = append(writeButtons, commitButton)
+	discardButton := widget.NewButtonWithIcon("Discard", theme.ContentRemoveIcon(), func() {
+		if selectedPendingIndex < 0 {
+			showErrorLog("No pending change selected")
+			return
+		}
+		id := stagingKey()
+		changes := stagedChanges[id]
+		stagedChanges[id] = append(changes[:selectedPendingIndex:selectedPendingIndex], changes[selectedPendingIndex+1:]...)
+		refreshPendingChanges()
+	})
+	discardAllButton := widget.NewButtonWithIcon("Discard All", theme.DeleteIcon(), func() {
+		if len(currentStagedChanges()) == 0 {
+			return
+		}
+		dialog.ShowConfirm("Discard All", "Discard all pending changes?", func(ok bool) {
+			if ok {
+				delete(stagedChanges, stagingKey())
+				refreshPendingChanges()
+				showInfoLog("Pending changes discarded")
+			}
+		}, w)
+	})
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		showKeyValesTabItem()
+	})
+
+	beforeLabel := widget.NewLabel("Before")
+	beforeLabel.TextStyle = fyne.TextStyle{Bold: true}
+	afterLabel := widget.NewLabel("After")
+	afterLabel.TextStyle = fyne.TextStyle{Bold: true}
+	diff := container.NewGridWithColumns(2,
+		container.NewBorder(beforeLabel, nil, nil, nil, pendingBeforeEntry),
+		container.NewBorder(afterLabel, nil, nil, nil, pendingAfterEntry))
+	split := container.NewHSplit(pendingList, diff)
+	split.Offset = 0.35
+
+	border := container.NewBorder(pendingSummaryLabel,
+		container.NewGridWithColumns(4, commitButton, discardButton, discardAllButton, backButton), nil, nil, split)
+	border.Hide()
+	return border
+}
+
+func showPendingTabItem() {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	err := tabTitle.Set("Pending Changes")
+	if err != nil {
+		return
+	}
+	refreshPendingChanges()
+	pendingTabItem.Show()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+// stagingKey 返回当前数据库在 stagedChanges 中的键
+func stagingKey() widget.TreeNodeID {
+	return dbiNodeID(selectedConnectionIndex, selectedDBIName)
+}
+
+// shiftStagedChanges 在删除连接后丢弃该连接的待提交修改，之后的连接的修改移到减一后的下标下
+func shiftStagedChanges(deletedIndex int) {
+	shifted := make(map[widget.TreeNodeID][]store.Change, len(stagedChanges))
+	for id, changes := range stagedChanges {
+		connectionIndex, name, _ := parseConnectionNodeID(id)
+		if connectionIndex == deletedIndex {
+			continue
+		}
+		if connectionIndex > deletedIndex {
+			id = dbiNodeID(connectionIndex-1, name)
+		}
+		shifted[id] = changes
+	}
+	stagedChanges = shifted
+}
+
+// currentStagedChanges 返回当前数据库待提交的修改
+func currentStagedChanges() []store.Change {
+	if selectedConnectionIndex == -1 {
+		return nil
+	}
+	return stagedChanges[stagingKey()]
+}
+
+// refreshPendingChanges 更新待提交修改列表和标题栏中的数量
+func refreshPendingChanges() {
+	changes := currentStagedChanges()
+	pendingButton.SetText(fmt.Sprintf("Pending (%d)", len(changes)))
+	if selectedConnectionIndex != -1 {
+		pendingSummaryLabel.SetText(fmt.Sprintf("%d pending changes in %s / %s", len(changes),
+			config.Config.Connections[selectedConnectionIndex].Name, dbiDisplayName(selectedDBIName)))
+	}
+	pendingList.UnselectAll()
+	pendingList.Refresh()
+}
+
+// stageChange 将修改加入当前数据库的待提交列表，并记录键当前的值用于显示差异和提交时检查冲突。
+// 非 DupSort 数据库中同一个键的多次修改合并为最后一次，保留最初记录的值
+func stageChange(change store.Change) {
+	err := env.View(func(txn *lmdb.Txn) (err error) {
+		change.Before, err = store.ReadValues(txn, dbi, change.Key)
+		return err
+	})
+	if err != nil {
+		showErrorLog("Error reading current value: " + err.Error())
+		return
+	}
+	id := stagingKey()
+	changes := stagedChanges[id]
+	if !dbiIsDupSort {
+		change.Verify = true
+		merged := false
+		for i, staged := range changes {
+			if bytes.Equal(staged.Key, change.Key) {
+				change.Before = staged.Before
+				changes = append(changes[:i:i], changes[i+1:]...)
+				merged = true
+				break
+			}
+		}
+		if change.Kind == store.ChangeDelete && len(change.Before) == 0 {
+			if !merged {
+				showErrorLog("Key " + keyCodec.Format(change.Key) + " does not exist")
+				return
+			}
+			// 删除之前暂存新增的键，两次修改相互抵消
+			stagedChanges[id] = changes
+			refreshPendingChanges()
+			showInfoLog("Staged changes to " + keyCodec.Format(change.Key) + " cancelled out")
+			return
+		}
+	}
+	stagedChanges[id] = append(changes, change)
+	refreshPendingChanges()
+	showInfoLog(fmt.Sprintf("Staged %s %s (%d pending)", change.Kind, keyCodec.Format(change.Key), len(stagedChanges[id])))
+}
+
+// commitStagedChanges 在一个写事务中提交当前数据库所有待提交的修改，任何一个失败时都不会写入
+func commitStagedChanges() {
+	if connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	changes := currentStagedChanges()
+	if len(changes) == 0 {
+		showInfoLog("No pending changes")
+		return
+	}
+	// 任何一个修改失败（包括键在暂存之后被其他程序修改）时整个事务回滚
+	applied, err := store.ApplyChanges(env, dbi, changes)
+	if err != nil {
+		showErrorLog("Error committing changes, nothing was written: " + err.Error())
+		return
+	}
+	recordHistory(applied)
+	delete(stagedChanges, stagingKey())
+	refreshPendingChanges()
+	showInfoLog(fmt.Sprintf("Committed %d changes", len(changes)))
+	totalRecordsCached = false
+	reloadKeyValues()
+}
+
+// stagedAfter 返回修改提交后键的所有值
+func stagedAfter(change store.Change) [][]byte {
+	values := make([][]byte, 0, len(change.Before)+1)
+	for _, v := range change.Before {
+		if (change.Kind == store.ChangeDeleteDup || change.Kind == store.ChangeReplaceDup) && bytes.Equal(v, change.Dup) {
+			continue
+		}
+		values = append(values, v)
+	}
+	switch change.Kind {
+	case store.ChangePut:
+		if !dbiIsDupSort {
+			return [][]byte{change.Value}
+		}
+		fallthrough
+	case store.ChangeReplaceDup:
+		for _, v := range values {
+			if bytes.Equal(v, change.Value) {
+				return values
+			}
+		}
+		values = append(values, change.Value)
+		sort.Slice(values, func(i, j int) bool { return bytes.Compare(values[i], values[j]) < 0 })
+	case store.ChangeDelete:
+		return nil
+	}
+	return values
+}
+
+// formatDiffValues 将键的所有值格式化为文本，DupSort 数据库中的多个值用分隔线隔开
+func formatDiffValues(values [][]byte) string {
+	if len(values) == 0 {
+		return "(absent)"
+	}
+	parts := make([]string, 0, len(values))
+	for i, v := range values {
+		if i == maxDiffValues {
+			parts = append(parts, fmt.Sprintf("... %d more values", len(values)-maxDiffValues))
+			break
+		}
+		parts = append(parts, formatValue(v))
+	}
+	return strings.Join(parts, "\n────────\n")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// 已使用空间超过映射大小的该比例时显示警告
+const mapUsageWarning = 0.8
+
+var statsTabItem *fyne.Container
+var statsContent *fyne.Container
+
+func initStatsTabItem() *fyne.Container {
+	statsContent = container.NewVBox()
+
+	refreshButton := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
+		refreshStats()
+	})
+	readersButton := widget.NewButtonWithIcon("Readers", theme.AccountIcon(), func() {
+		showReadersTabItem()
+	})
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		showKeyValesTabItem()
+	})
+
+	border := container.NewBorder(nil, container.NewGridWithColumns(3, refreshButton, readersButton, backButton), nil, nil,
+		container.NewVScroll(statsContent))
+	border.Hide()
+	return border
+}
+
+func showStatsTabItem() {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	err := tabTitle.Set("Statistics")
+	if err != nil {
+		return
+	}
+	refreshStats()
+	statsTabItem.Show()
+	readersTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	pendingTabItem.Hide()
+	historyTabItem.Hide()
+	compareTabItem.Hide()
+	transformTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+// refreshStats 重新读取当前连接和数据库的统计信息
+func refreshStats() {
+	stats, err := store.ReadStats(env, dbi)
+	if err != nil {
+		showErrorLog("Error reading statistics: " + err.Error())
+		return
+	}
+	connection := config.Config.Connections[selectedConnectionIndex]
+
+	// 按连接中配置的映射大小计算使用率，未配置时使用环境实际的映射大小
+	mapSize := stats.Info.MapSize
+	if connection.MapSize > 0 {
+		mapSize = 1 << 30 * connection.MapSize
+	}
+	usage := float64(stats.UsedSize) / float64(mapSize)
+	usageBar := widget.NewProgressBar()
+	usageBar.SetValue(usage)
+
+	envForm := widget.NewForm(
+		statsItem("Path", connection.DatabasePath),
+		statsItem("Map Size", formatBytes(mapSize)),
+		statsItem("Used", fmt.Sprintf("%s (%.1f%%)", formatBytes(stats.UsedSize), usage*100)),
+		widget.NewFormItem("Map Usage", usageBar),
+		statsItem("Page Size", formatBytes(int64(stats.Env.PSize))),
+		statsItem("Last Page", strconv.FormatInt(stats.Info.LastPNO, 10)),
+		statsItem("Last Txn ID", strconv.FormatInt(stats.Info.LastTxnID, 10)),
+		statsItem("Readers", fmt.Sprintf("%d / %d", stats.Info.NumReaders, stats.Info.MaxReaders)),
+		statsItem("Root Depth", strconv.FormatUint(uint64(stats.Env.Depth), 10)),
+		statsItem("Root Entries", strconv.FormatUint(stats.Env.Entries, 10)),
+	)
+	dbiForm := widget.NewForm(
+		statsItem("Name", dbiDisplayName(selectedDBIName)),
+		statsItem("Flags", store.FormatDBIFlags(stats.DBIFlags)),
+		statsItem("Entries", strconv.FormatUint(stats.DBI.Entries, 10)),
+		statsItem("Depth", strconv.FormatUint(uint64(stats.DBI.Depth), 10)),
+		statsItem("Branch Pages", strconv.FormatUint(stats.DBI.BranchPages, 10)),
+		statsItem("Leaf Pages", strconv.FormatUint(stats.DBI.LeafPages, 10)),
+		statsItem("Overflow Pages", strconv.FormatUint(stats.DBI.OverflowPages, 10)),
+		statsItem("Size", formatBytes(int64(store.Pages(stats.DBI))*int64(stats.DBI.PSize))),
+	)
+
+	statsContent.Objects = nil
+	if usage >= mapUsageWarning {
+		warning := widget.NewLabel(fmt.Sprintf("Map usage is %.1f%%, increase the map size of this connection before the database is full (MDB_MAP_FULL).", usage*100))
+		warning.Importance = widget.DangerImportance
+		warning.Wrapping = fyne.TextWrapWord
+		statsContent.Add(container.NewBorder(nil, nil, widget.NewIcon(theme.WarningIcon()), nil, warning))
+	}
+	statsContent.Add(widget.NewCard("Environment", connection.Name, envForm))
+	statsContent.Add(widget.NewCard("Database", "", dbiForm))
+	statsContent.Refresh()
+}
+
+func statsItem(name, value string) *widget.FormItem {
+	label := widget.NewLabel(value)
+	label.TextStyle = fyne.TextStyle{Monospace: true}
+	return widget.NewFormItem(name, label)
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"os"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// DataFileName 为 LMDB 环境目录中的数据文件名
+const DataFileName = "data.mdb"
+
+// Backup 将环境的一致快照写入目录 dir 中的 data.mdb，目录不存在时会被创建。
+// 复制在读事务中进行，期间数据库仍可正常读写；compact 为 true 时省略空闲页并重新编号，得到更小的文件
+func Backup(env *lmdb.Env, dir string, compact bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var flags uint
+	if compact {
+		flags = lmdb.CopyCompact
+	}
+	return env.CopyFlag(dir, flags)
+}
+
+// UsedSize 返回环境已使用的页所占的字节数
+func UsedSize(env *lmdb.Env) (int64, error) {
+	info, err := env.Info()
+	if err != nil {
+		return 0, err
+	}
+	stat, err := env.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return (info.LastPNO + 1) * int64(stat.PSize), nil
+}
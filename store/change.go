@@ -0,0 +1,164 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// ChangeKind 为写操作的类型
+type ChangeKind int
+
+const (
+	// ChangePut 写入键值，DupSort 数据库中为新增一个重复值
+	ChangePut ChangeKind = iota
+	// ChangeDelete 删除键，DupSort 数据库中删除该键的所有重复值
+	ChangeDelete
+	// ChangeDeleteDup 删除 DupSort 数据库中的单个重复值 Dup
+	ChangeDeleteDup
+	// ChangeReplaceDup 将 DupSort 数据库中的重复值 Dup 替换为 Value
+	ChangeReplaceDup
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangePut:
+		return "Put"
+	case ChangeDelete:
+		return "Delete"
+	case ChangeDeleteDup:
+		return "Delete Dup"
+	case ChangeReplaceDup:
+		return "Replace Dup"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change 为一次写操作
+type Change struct {
+	Kind  ChangeKind `json:"kind"`
+	Key   []byte     `json:"key"`
+	Value []byte     `json:"value,omitempty"` // ChangePut、ChangeReplaceDup 写入的值
+	Dup   []byte     `json:"dup,omitempty"`   // ChangeDeleteDup、ChangeReplaceDup 原来的重复值
+
+	// Before 和 After 为写入前后键的所有值（非 DupSort 数据库最多一个），键不存在时为空，由 ApplyChanges 填写。
+	// Verify 为 true 时 ApplyChanges 先检查键当前的值与 Before 一致
+	Before [][]byte `json:"before"`
+	After  [][]byte `json:"after"`
+	Verify bool     `json:"-"`
+}
+
+// ConflictError 表示键的值与预期的不一致，例如在暂存之后已被修改
+type ConflictError struct {
+	Key []byte
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("key %q has been modified since it was read", e.Key)
+}
+
+// ApplyChanges 在一个写事务中依次应用 changes，任何一个失败时整个事务回滚，所有修改都不会写入。
+// 返回填写了 Before 和 After 的 changes 副本
+func ApplyChanges(env *lmdb.Env, dbi lmdb.DBI, changes []Change) ([]Change, error) {
+	applied := make([]Change, len(changes))
+	err := env.Update(func(txn *lmdb.Txn) error {
+		for i, change := range changes {
+			before, err := ReadValues(txn, dbi, change.Key)
+			if err != nil {
+				return err
+			}
+			if change.Verify && !EqualValues(before, change.Before) {
+				return &ConflictError{Key: change.Key}
+			}
+			if err := change.apply(txn, dbi); err != nil {
+				return fmt.Errorf("%s %q: %w", change.Kind, change.Key, err)
+			}
+			after, err := ReadValues(txn, dbi, change.Key)
+			if err != nil {
+				return err
+			}
+			change.Before, change.After = before, after
+			applied[i] = change
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (c Change) apply(txn *lmdb.Txn, dbi lmdb.DBI) error {
+	switch c.Kind {
+	case ChangePut:
+		return txn.Put(dbi, c.Key, c.Value, 0)
+	case ChangeDelete:
+		return DeleteKey(txn, dbi, c.Key)
+	case ChangeDeleteDup:
+		return txn.Del(dbi, c.Key, c.Dup)
+	case ChangeReplaceDup:
+		if err := txn.Del(dbi, c.Key, c.Dup); err != nil {
+			return err
+		}
+		return txn.Put(dbi, c.Key, c.Value, 0)
+	}
+	return fmt.Errorf("unknown change kind %d", int(c.Kind))
+}
+
+// SetValues 生成将键的所有值从 from 改为 to 的修改，应用时先检查键当前的值仍为 from
+func SetValues(key []byte, from, to [][]byte) []Change {
+	changes := make([]Change, 0, len(to)+1)
+	if len(from) > 0 {
+		changes = append(changes, Change{Kind: ChangeDelete, Key: key, Before: from, Verify: true})
+	}
+	for i, v := range to {
+		change := Change{Kind: ChangePut, Key: key, Value: v}
+		if i == 0 && len(from) == 0 {
+			change.Verify = true
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// EqualValues 判断两组值是否相同
+func EqualValues(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadValues 读取键的所有值，DupSort 数据库中按顺序返回所有重复值，键不存在时返回空
+func ReadValues(txn *lmdb.Txn, dbi lmdb.DBI, key []byte) ([][]byte, error) {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close()
+
+	flags, err := txn.Flags(dbi)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, 1)
+	_, v, err := cur.Get(key, nil, lmdb.Set)
+	for err == nil {
+		values = append(values, append([]byte(nil), v...))
+		// 非 DupSort 数据库中 MDB_NEXT_DUP 会移动到下一个键
+		if flags&lmdb.DupSort == 0 {
+			return values, nil
+		}
+		_, v, err = cur.Get(nil, nil, lmdb.NextDup)
+	}
+	if lmdb.IsNotFound(err) {
+		return values, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,280 @@
+package store
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// KeyRange 为键的过滤范围：以 Prefix 开头，且位于 [Start, End) 之间。
+// 各项为空时表示不限制；Descending 为 true 时从大到小遍历
+type KeyRange struct {
+	Prefix     []byte
+	Start      []byte // 包含
+	End        []byte // 不包含
+	Descending bool
+}
+
+// IsEmpty 表示范围不做任何限制
+func (r KeyRange) IsEmpty() bool {
+	return len(r.Prefix) == 0 && len(r.Start) == 0 && len(r.End) == 0
+}
+
+// Contains 判断键是否在范围内
+func (r KeyRange) Contains(key []byte) bool {
+	lower, upper := r.bounds()
+	return bytes.Compare(key, lower) >= 0 && (upper == nil || bytes.Compare(key, upper) < 0)
+}
+
+// Reverse 返回遍历方向相反的同一范围
+func (r KeyRange) Reverse() KeyRange {
+	r.Descending = !r.Descending
+	return r
+}
+
+// bounds 返回范围的下界（包含）和上界（不包含），上界为 nil 表示没有上界
+func (r KeyRange) bounds() (lower, upper []byte) {
+	lower = r.Prefix
+	if bytes.Compare(r.Start, lower) > 0 {
+		lower = r.Start
+	}
+	upper = prefixEnd(r.Prefix)
+	if len(r.End) > 0 && (upper == nil || bytes.Compare(r.End, upper) < 0) {
+		upper = r.End
+	}
+	return lower, upper
+}
+
+// prefixEnd 返回大于所有以 prefix 开头的键的最小键，prefix 为空或全为 0xff 时返回 nil
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// KeyCursor 按范围和方向遍历键值对。uniqueKeys 为 true 时 DupSort 数据库中每个键只返回一次（第一个重复值）
+type KeyCursor struct {
+	cur        *lmdb.Cursor
+	keyRange   KeyRange
+	uniqueKeys bool
+	lower      []byte
+	upper      []byte
+	started    bool
+	done       bool
+}
+
+// NewKeyCursor 在 txn 中打开 dbi 的游标，使用完毕后必须调用 Close
+func NewKeyCursor(txn *lmdb.Txn, dbi lmdb.DBI, keyRange KeyRange, uniqueKeys bool) (*KeyCursor, error) {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return nil, err
+	}
+	c := &KeyCursor{cur: cur, keyRange: keyRange, uniqueKeys: uniqueKeys}
+	c.lower, c.upper = keyRange.bounds()
+	return c, nil
+}
+
+// Cursor 返回底层的游标，例如用于读取当前键的重复值数量
+func (c *KeyCursor) Cursor() *lmdb.Cursor {
+	return c.cur
+}
+
+// Close 关闭游标
+func (c *KeyCursor) Close() {
+	c.cur.Close()
+}
+
+// Next 返回下一条记录，超出范围或遍历完毕时 ok 为 false
+func (c *KeyCursor) Next() (key, val []byte, ok bool, err error) {
+	if c.done {
+		return nil, nil, false, nil
+	}
+	if !c.started {
+		c.started = true
+		key, val, err = c.first()
+	} else {
+		key, val, err = c.cur.Get(nil, nil, c.nextOp())
+		if err == nil && c.keyRange.Descending && c.uniqueKeys {
+			key, val, err = c.firstDup(key, val)
+		}
+	}
+	return c.check(key, val, err)
+}
+
+// Seek 将游标定位到遍历方向上位于 key 处（inclusive 为 true）或 key 之后的第一条记录并返回该记录，
+// 之后调用 Next 继续遍历。key 超出范围时定位到范围的起点。定位只按键进行，
+// 对 DupSort 数据库应使用 uniqueKeys 为 true 的游标
+func (c *KeyCursor) Seek(key []byte, inclusive bool) ([]byte, []byte, bool, error) {
+	c.started = true
+	c.done = false
+	var k, v []byte
+	var err error
+	if !c.keyRange.Descending {
+		if bytes.Compare(key, c.lower) < 0 {
+			key, inclusive = c.lower, true
+		}
+		k, v, err = c.seekUp(key, inclusive)
+	} else {
+		if c.upper != nil && bytes.Compare(key, c.upper) >= 0 {
+			key, inclusive = c.upper, false
+		}
+		k, v, err = c.seekDown(key, inclusive)
+	}
+	return c.check(k, v, err)
+}
+
+func (c *KeyCursor) first() ([]byte, []byte, error) {
+	if !c.keyRange.Descending {
+		if len(c.lower) > 0 {
+			return c.seekUp(c.lower, true)
+		}
+		return c.cur.Get(nil, nil, lmdb.First)
+	}
+	if c.upper == nil {
+		key, val, err := c.cur.Get(nil, nil, lmdb.Last)
+		if err == nil && c.uniqueKeys {
+			return c.firstDup(key, val)
+		}
+		return key, val, err
+	}
+	// 定位到上界之前的最后一个键
+	return c.seekDown(c.upper, false)
+}
+
+// seekUp 定位到不小于 key（inclusive 为 false 时大于 key）的第一个键
+func (c *KeyCursor) seekUp(key []byte, inclusive bool) ([]byte, []byte, error) {
+	k, v, err := c.cur.Get(key, nil, lmdb.SetRange)
+	if err == nil && !inclusive && bytes.Equal(k, key) {
+		k, v, err = c.cur.Get(nil, nil, c.nextOp())
+	}
+	return k, v, err
+}
+
+// seekDown 定位到不大于 key（inclusive 为 false 时小于 key）的最后一个键
+func (c *KeyCursor) seekDown(key []byte, inclusive bool) ([]byte, []byte, error) {
+	k, v, err := c.cur.Get(key, nil, lmdb.SetRange)
+	if lmdb.IsNotFound(err) {
+		k, v, err = c.cur.Get(nil, nil, lmdb.Last)
+	} else if err == nil && (!inclusive || !bytes.Equal(k, key)) {
+		k, v, err = c.cur.Get(nil, nil, c.prevOp())
+	}
+	if err == nil && c.uniqueKeys {
+		return c.firstDup(k, v)
+	}
+	return k, v, err
+}
+
+// firstDup 在 DupSort 数据库中降序遍历时移动到当前键的第一个重复值，与升序时显示的值保持一致
+func (c *KeyCursor) firstDup(key, val []byte) ([]byte, []byte, error) {
+	// MDB_FIRST_DUP 不返回键，键保持不变
+	_, v, err := c.cur.Get(nil, nil, lmdb.FirstDup)
+	if lmdb.IsErrno(err, lmdb.Incompatible) {
+		// 不是 DupSort 数据库
+		return key, val, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, v, nil
+}
+
+func (c *KeyCursor) nextOp() uint {
+	if c.keyRange.Descending {
+		return c.prevOp()
+	}
+	if c.uniqueKeys {
+		return lmdb.NextNoDup
+	}
+	return lmdb.Next
+}
+
+func (c *KeyCursor) prevOp() uint {
+	if c.uniqueKeys {
+		return lmdb.PrevNoDup
+	}
+	return lmdb.Prev
+}
+
+func (c *KeyCursor) check(key, val []byte, err error) ([]byte, []byte, bool, error) {
+	if lmdb.IsNotFound(err) {
+		c.done = true
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		c.done = true
+		return nil, nil, false, err
+	}
+	if !c.keyRange.Contains(key) {
+		c.done = true
+		return nil, nil, false, nil
+	}
+	return key, val, true, nil
+}
+
+// ScanRange 在 txn 中按范围和方向遍历所有键值对，DupSort 数据库的每个重复值各调用一次 fn。
+// fn 收到的 key 和 val 只在本次调用中有效，fn 返回错误时停止遍历并返回该错误
+func ScanRange(txn *lmdb.Txn, dbi lmdb.DBI, keyRange KeyRange, fn func(key, val []byte) error) error {
+	cursor, err := NewKeyCursor(txn, dbi, keyRange, false)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for {
+		key, val, ok, err := cursor.Next()
+		if err != nil || !ok {
+			return err
+		}
+		if err := fn(key, val); err != nil {
+			return err
+		}
+	}
+}
+
+// countCheckInterval 为计数时两次检查 ctx 是否取消之间遍历的记录数
+const countCheckInterval = 1000
+
+// CountKeys 在一个读事务中统计范围内的记录数，见 CountRange
+func CountKeys(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, keyRange KeyRange, uniqueKeys bool) (int, error) {
+	count := 0
+	err := env.View(func(txn *lmdb.Txn) (err error) {
+		count, err = CountRange(ctx, txn, dbi, keyRange, uniqueKeys)
+		return err
+	})
+	return count, err
+}
+
+// CountRange 统计 txn 中范围内的记录数，uniqueKeys 为 true 时 DupSort 数据库中每个键只计一次。
+// 范围不做限制且按重复值计数时直接使用 Stat 中的记录数，否则需要遍历范围，ctx 被取消时返回 ctx.Err()
+func CountRange(ctx context.Context, txn *lmdb.Txn, dbi lmdb.DBI, keyRange KeyRange, uniqueKeys bool) (int, error) {
+	if keyRange.IsEmpty() && !uniqueKeys {
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return 0, err
+		}
+		return int(stat.Entries), nil
+	}
+	cursor, err := NewKeyCursor(txn, dbi, keyRange, uniqueKeys)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close()
+	count := 0
+	for {
+		_, _, ok, err := cursor.Next()
+		if err != nil || !ok {
+			return count, err
+		}
+		count++
+		if count%countCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+		}
+	}
+}
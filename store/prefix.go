@@ -0,0 +1,189 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// PrefixOperation 为对一个前缀下所有键的批量操作
+type PrefixOperation int
+
+const (
+	// PrefixDelete 删除前缀下的所有键
+	PrefixDelete PrefixOperation = iota
+	// PrefixCopy 将前缀下的所有键复制到新的前缀
+	PrefixCopy
+	// PrefixMove 将前缀下的所有键移动到新的前缀（复制后删除原来的键）
+	PrefixMove
+)
+
+func (o PrefixOperation) String() string {
+	switch o {
+	case PrefixDelete:
+		return "Delete"
+	case PrefixCopy:
+		return "Copy"
+	case PrefixMove:
+		return "Move"
+	}
+	return fmt.Sprintf("PrefixOperation(%d)", int(o))
+}
+
+// PrefixOperations 返回所有前缀操作
+func PrefixOperations() []PrefixOperation {
+	return []PrefixOperation{PrefixDelete, PrefixCopy, PrefixMove}
+}
+
+var errBatchFull = errors.New("batch full")
+
+// KeyExistsError 表示复制或移动的目标键已经存在
+type KeyExistsError struct {
+	Key []byte
+}
+
+func (e *KeyExistsError) Error() string {
+	return fmt.Sprintf("target key %q already exists", e.Key)
+}
+
+// ReplacePrefix 返回将 key 开头的 prefix 替换为 newPrefix 后的键
+func ReplacePrefix(key, prefix, newPrefix []byte) []byte {
+	target := make([]byte, 0, len(newPrefix)+len(key)-len(prefix))
+	return append(append(target, newPrefix...), key[len(prefix):]...)
+}
+
+// CheckPrefixOperation 检查操作的参数：前缀不能为空，复制和移动时新前缀与原前缀不能互为前缀，
+// 否则写入的键会落在正在遍历的范围内或覆盖原来的键
+func CheckPrefixOperation(op PrefixOperation, prefix, newPrefix []byte) error {
+	if len(prefix) == 0 {
+		return errors.New("the key prefix is empty")
+	}
+	if op == PrefixDelete {
+		return nil
+	}
+	if len(newPrefix) == 0 {
+		return errors.New("the new prefix is empty")
+	}
+	if bytes.HasPrefix(newPrefix, prefix) || bytes.HasPrefix(prefix, newPrefix) {
+		return errors.New("the new prefix overlaps the key prefix")
+	}
+	return nil
+}
+
+// ListPrefixKeys 返回前缀下的前 limit 个键，DupSort 数据库中每个键只返回一次
+func ListPrefixKeys(env *lmdb.Env, dbi lmdb.DBI, prefix []byte, limit int) ([][]byte, error) {
+	var keys [][]byte
+	err := env.View(func(txn *lmdb.Txn) error {
+		return ScanRange(txn, dbi, KeyRange{Prefix: prefix}, func(key, val []byte) error {
+			if len(keys) > 0 && bytes.Equal(keys[len(keys)-1], key) {
+				return nil
+			}
+			if len(keys) == limit {
+				return errBatchFull
+			}
+			keys = append(keys, append([]byte(nil), key...))
+			return nil
+		})
+	})
+	if errors.Is(err, errBatchFull) {
+		err = nil
+	}
+	return keys, err
+}
+
+// ApplyPrefixOperation 对前缀下的所有键执行 op，每 batchSize 个键（包括其所有重复值）在一个写事务中提交一次。
+// 复制和移动时目标键已存在则停止并返回 *KeyExistsError。出错时当前批次回滚，之前的批次保持已提交；
+// ctx 被取消时在批次之间停止。每提交一个批次调用一次 progress，参数为已处理的键数和本批次的修改，
+// 修改中填写了写入前后键的所有值，可以加入历史
+func ApplyPrefixOperation(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, op PrefixOperation, prefix, newPrefix []byte,
+	batchSize int, progress func(keys int, changes []Change)) (int, error) {
+	if err := CheckPrefixOperation(op, prefix, newPrefix); err != nil {
+		return 0, err
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	done := 0
+	keyRange := KeyRange{Prefix: prefix}
+	for {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		var keys [][]byte
+		var values [][][]byte
+		var changes []Change
+		err := env.Update(func(txn *lmdb.Txn) error {
+			// 先读出一批键值，再在同一个事务中写入，遍历时不修改数据库
+			err := ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+				if len(keys) > 0 && bytes.Equal(keys[len(keys)-1], key) {
+					values[len(values)-1] = append(values[len(values)-1], append([]byte(nil), val...))
+					return nil
+				}
+				if len(keys) == batchSize {
+					return errBatchFull
+				}
+				keys = append(keys, append([]byte(nil), key...))
+				values = append(values, [][]byte{append([]byte(nil), val...)})
+				return nil
+			})
+			if err != nil && !errors.Is(err, errBatchFull) {
+				return err
+			}
+			changes = make([]Change, 0, len(keys))
+			for i, key := range keys {
+				applied, err := applyPrefixOperation(txn, dbi, op, key, values[i], prefix, newPrefix)
+				if err != nil {
+					return err
+				}
+				changes = append(changes, applied...)
+			}
+			return nil
+		})
+		if err != nil {
+			return done, err
+		}
+		if len(keys) == 0 {
+			return done, nil
+		}
+		done += len(keys)
+		if progress != nil {
+			progress(done, changes)
+		}
+		if len(keys) < batchSize {
+			return done, nil
+		}
+		// 复制时原来的键仍然存在，下一批从本批最后一个键之后开始
+		keyRange.Start = append(keys[len(keys)-1], 0)
+	}
+}
+
+// applyPrefixOperation 对一个键执行 op，返回的修改中目标键原来不存在，写入后为原来的键的所有值，删除的键写入后为空
+func applyPrefixOperation(txn *lmdb.Txn, dbi lmdb.DBI, op PrefixOperation, key []byte, values [][]byte, prefix, newPrefix []byte) ([]Change, error) {
+	var changes []Change
+	if op != PrefixDelete {
+		target := ReplacePrefix(key, prefix, newPrefix)
+		_, err := txn.Get(dbi, target)
+		if err == nil {
+			return nil, &KeyExistsError{Key: target}
+		}
+		if !lmdb.IsNotFound(err) {
+			return nil, err
+		}
+		for _, val := range values {
+			if err := txn.Put(dbi, target, val, 0); err != nil {
+				return nil, fmt.Errorf("put %q: %w", target, err)
+			}
+		}
+		changes = append(changes, Change{Kind: ChangePut, Key: target, After: values})
+	}
+	if op != PrefixCopy {
+		if err := DeleteKey(txn, dbi, key); err != nil {
+			return nil, fmt.Errorf("delete %q: %w", key, err)
+		}
+		changes = append(changes, Change{Kind: ChangeDelete, Key: key, Before: values})
+	}
+	return changes, nil
+}
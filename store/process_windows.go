@@ -0,0 +1,15 @@
+//go:build windows
+
+package store
+
+import "os"
+
+// processAlive 判断进程是否存在，Windows 上找不到进程时 FindProcess 会返回错误
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// Reader 为锁文件读者表中的一项
+type Reader struct {
+	PID    int
+	Thread string // 线程 ID（十六进制）
+	TxnID  int64  // 读事务 ID，没有活动的读事务时为 -1
+	Alive  bool   // 进程是否仍然存在，不存在的进程占用的是过期的读者槽位
+	Own    bool   // 是否为当前进程
+}
+
+// ListReaders 读取环境的读者表（mdb_reader_list）
+func ListReaders(env *lmdb.Env) ([]Reader, error) {
+	readers := make([]Reader, 0)
+	err := env.ReaderList(func(line string) error {
+		// 每行格式为 "pid thread txnid"，表头和 "(no active readers)" 等提示行会被跳过
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil
+		}
+		reader := Reader{PID: pid, Thread: fields[1], TxnID: -1}
+		if fields[2] != "-" {
+			reader.TxnID, _ = strconv.ParseInt(fields[2], 10, 64)
+		}
+		reader.Own = pid == os.Getpid()
+		reader.Alive = reader.Own || processAlive(pid)
+		readers = append(readers, reader)
+		return nil
+	})
+	return readers, err
+}
+
+// ClearStaleReaders 清除进程已不存在的读者槽位（mdb_reader_check），返回清除的数量
+func ClearStaleReaders(env *lmdb.Env) (int, error) {
+	return env.ReaderCheck()
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// Stats 为环境和数据库的统计信息
+type Stats struct {
+	Info     *lmdb.EnvInfo
+	Env      *lmdb.Stat // 根数据库的 B 树统计
+	DBI      *lmdb.Stat
+	DBIFlags uint
+	UsedSize int64 // 已使用的页所占的字节数
+}
+
+// ReadStats 读取环境和 dbi 的统计信息
+func ReadStats(env *lmdb.Env, dbi lmdb.DBI) (*Stats, error) {
+	stats := &Stats{}
+	var err error
+	if stats.Info, err = env.Info(); err != nil {
+		return nil, err
+	}
+	if stats.Env, err = env.Stat(); err != nil {
+		return nil, err
+	}
+	err = env.View(func(txn *lmdb.Txn) error {
+		if stats.DBI, err = txn.Stat(dbi); err != nil {
+			return err
+		}
+		stats.DBIFlags, err = txn.Flags(dbi)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats.UsedSize, err = UsedSize(env); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Pages 返回 B 树的总页数
+func Pages(stat *lmdb.Stat) uint64 {
+	return stat.BranchPages + stat.LeafPages + stat.OverflowPages
+}
+
+// FormatDBIFlags 将数据库标志格式化为 "DUPSORT|DUPFIXED" 的形式，没有标志时返回 "-"
+func FormatDBIFlags(flags uint) string {
+	names := DBIFlagNames(flags)
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.ToUpper(strings.Join(names, "|"))
+}
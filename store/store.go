@@ -0,0 +1,140 @@
+package store
+
+import (
+	"bytes"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+	"github.com/PowerDNS/lmdb-go/lmdbscan"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+)
+
+// RootDBIName 表示未命名的根数据库
+const RootDBIName = ""
+
+// OpenEnv 根据连接配置打开 LMDB 环境
+func OpenEnv(connection config.ConnectionConfig) (*lmdb.Env, error) {
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	err = env.SetMapSize(1 << 30 * connection.MapSize)
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	err = env.SetMaxDBs(connection.MaxDBs)
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	err = env.Open(connection.DatabasePath, OpenFlags(connection.ReadOnly), 0664)
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	return env, nil
+}
+
+// OpenFlags 返回打开环境时使用的标志，只读连接不会获取写锁也不会创建文件
+func OpenFlags(readOnly bool) uint {
+	if readOnly {
+		return lmdb.Readonly
+	}
+	return 0
+}
+
+// OpenDBI 打开指定名称的数据库，名称为空时打开根数据库
+func OpenDBI(env *lmdb.Env, name string) (lmdb.DBI, error) {
+	var dbi lmdb.DBI
+	err := env.View(func(txn *lmdb.Txn) (err error) {
+		if name == RootDBIName {
+			dbi, err = txn.OpenRoot(0)
+		} else {
+			dbi, err = txn.OpenDBI(name, 0)
+		}
+		return err
+	})
+	return dbi, err
+}
+
+// ListDBINames 列出根数据库中所有的命名数据库
+func ListDBINames(env *lmdb.Env, maxDBs int) ([]string, error) {
+	names := make([]string, 0)
+	if maxDBs <= 0 {
+		// 未设置 MaxDBs 时无法打开命名数据库
+		return names, nil
+	}
+	err := env.View(func(txn *lmdb.Txn) error {
+		root, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		scanner := lmdbscan.New(txn, root)
+		defer scanner.Close()
+		for scanner.Scan() {
+			key := scanner.Key()
+			if len(key) == 0 || bytes.IndexByte(key, 0) >= 0 {
+				continue
+			}
+			// 根数据库中的普通键无法作为数据库打开，跳过即可
+			if _, err := txn.OpenDBI(string(key), 0); err != nil {
+				if lmdb.IsErrno(err, lmdb.DBsFull) {
+					break
+				}
+				continue
+			}
+			names = append(names, string(key))
+		}
+		return scanner.Err()
+	})
+	return names, err
+}
+
+// IsDupSort 判断数据库是否使用 MDB_DUPSORT 标志打开
+func IsDupSort(env *lmdb.Env, dbi lmdb.DBI) (bool, error) {
+	var flags uint
+	err := env.View(func(txn *lmdb.Txn) (err error) {
+		flags, err = txn.Flags(dbi)
+		return err
+	})
+	return flags&lmdb.DupSort != 0, err
+}
+
+// DeleteKey 删除键及其所有重复值。txn.Del 传入的空值不是 NULL，DupSort 数据库会按空的重复值查找而返回 MDB_BAD_VALSIZE，
+// 所以用游标定位到键后以 MDB_NODUPDATA 删除
+func DeleteKey(txn *lmdb.Txn, dbi lmdb.DBI, key []byte) error {
+	cur, err := txn.OpenCursor(dbi)
+	if err != nil {
+		return err
+	}
+	defer cur.Close()
+	if _, _, err := cur.Get(key, nil, lmdb.Set); err != nil {
+		return err
+	}
+	return cur.Del(lmdb.NoDupData)
+}
+
+// dbiFlags 为数据库标志及其名称，名称与 mdb_dump 输出的一致
+var dbiFlags = []struct {
+	flag uint
+	name string
+}{
+	{lmdb.ReverseKey, "reversekey"},
+	{lmdb.DupSort, "dupsort"},
+	{lmdb.IntegerKey, "integerkey"},
+	{lmdb.DupFixed, "dupfixed"},
+	{lmdb.IntegerDup, "integerdup"},
+	{lmdb.ReverseDup, "reversedup"},
+}
+
+// DBIFlagNames 返回 flags 中各个数据库标志的名称，例如 ["dupsort", "dupfixed"]
+func DBIFlagNames(flags uint) []string {
+	names := make([]string, 0)
+	for _, flag := range dbiFlags {
+		if flags&flag.flag != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+)
+
+// openTestDBI 在临时目录中创建环境并打开名为 name 的数据库
+func openTestDBI(t *testing.T, name string, flags uint) (*lmdb.Env, lmdb.DBI) {
+	t.Helper()
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err := env.SetMaxDBs(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Open(t.TempDir(), 0, 0664); err != nil {
+		t.Fatal(err)
+	}
+	var dbi lmdb.DBI
+	err = env.Update(func(txn *lmdb.Txn) (err error) {
+		dbi, err = txn.OpenDBI(name, flags|lmdb.Create)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, dbi
+}
+
+func putTestValues(t *testing.T, env *lmdb.Env, dbi lmdb.DBI, key string, values ...string) {
+	t.Helper()
+	err := env.Update(func(txn *lmdb.Txn) error {
+		for _, v := range values {
+			if err := txn.Put(dbi, []byte(key), []byte(v), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteKey(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		flags uint
+	}{
+		{"plain", 0},
+		{"dupsort", lmdb.DupSort},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			env, dbi := openTestDBI(t, tt.name, tt.flags)
+			putTestValues(t, env, dbi, "a", "1", "2", "3")
+			putTestValues(t, env, dbi, "b", "4")
+
+			err := env.Update(func(txn *lmdb.Txn) error {
+				return DeleteKey(txn, dbi, []byte("a"))
+			})
+			if err != nil {
+				t.Fatalf("DeleteKey: %v", err)
+			}
+			err = env.View(func(txn *lmdb.Txn) error {
+				// 键的所有重复值都已删除
+				if _, err := txn.Get(dbi, []byte("a")); !lmdb.IsNotFound(err) {
+					t.Errorf("Get(a) = %v, want not found", err)
+				}
+				val, err := txn.Get(dbi, []byte("b"))
+				if err != nil {
+					return err
+				}
+				if string(val) != "4" {
+					t.Errorf("b = %q, want 4", val)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = env.Update(func(txn *lmdb.Txn) error {
+				return DeleteKey(txn, dbi, []byte("missing"))
+			})
+			if !lmdb.IsNotFound(err) {
+				t.Errorf("DeleteKey(missing) = %v, want not found", err)
+			}
+		})
+	}
+}
+
+func TestApplyPrefixOperationChanges(t *testing.T) {
+	env, dbi := openTestDBI(t, "dupsort", lmdb.DupSort)
+	putTestValues(t, env, dbi, "a:1", "x", "y")
+	putTestValues(t, env, dbi, "a:2", "z")
+	putTestValues(t, env, dbi, "a:3", "w")
+	putTestValues(t, env, dbi, "b", "v")
+
+	var batches [][]Change
+	done, err := ApplyPrefixOperation(context.Background(), env, dbi, PrefixMove, []byte("a:"), []byte("c:"), 2,
+		func(keys int, changes []Change) {
+			batches = append(batches, changes)
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done != 3 || len(batches) != 2 {
+		t.Fatalf("moved %d keys in %d batches, want 3 keys in 2 batches", done, len(batches))
+	}
+	first := batches[0]
+	if len(first) != 4 || string(first[0].Key) != "c:1" || string(first[1].Key) != "a:1" {
+		t.Fatalf("first batch = %+v", first)
+	}
+	if len(first[0].Before) != 0 || !EqualValues(first[0].After, [][]byte{[]byte("x"), []byte("y")}) {
+		t.Errorf("c:1 before %q after %q", first[0].Before, first[0].After)
+	}
+	if !EqualValues(first[1].Before, [][]byte{[]byte("x"), []byte("y")}) || len(first[1].After) != 0 {
+		t.Errorf("a:1 before %q after %q", first[1].Before, first[1].After)
+	}
+
+	// 按历史撤销的方式逆序恢复修改前的值
+	var undo []Change
+	for i := len(batches) - 1; i >= 0; i-- {
+		for j := len(batches[i]) - 1; j >= 0; j-- {
+			change := batches[i][j]
+			undo = append(undo, SetValues(change.Key, change.After, change.Before)...)
+		}
+	}
+	if _, err := ApplyChanges(env, dbi, undo); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	keys, err := ListPrefixKeys(env, dbi, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, key := range keys {
+		got = append(got, string(key))
+	}
+	if strings.Join(got, ",") != "a:1,a:2,a:3,b" {
+		t.Errorf("keys after undo = %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// progressStep 为两次进度回调之间处理的记录数
+const progressStep = 1000
+
+// Export 按 keyRange 的范围和方向将 dbi 中的键值对写入 w，DupSort 数据库的每个重复值各占一条记录。
+// 所有记录在同一个读事务中读取，得到一致的快照；每处理 progressStep 条记录调用一次 progress，
+// ctx 被取消时停止导出并返回 ctx.Err()。返回已写入的记录数
+func Export(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, keyRange store.KeyRange, w Writer, progress func(done int)) (int, error) {
+	done := 0
+	err := env.View(func(txn *lmdb.Txn) error {
+		return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+			if err := w.Write(key, val); err != nil {
+				return err
+			}
+			done++
+			if done%progressStep == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if progress != nil {
+					progress(done)
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return done, err
+	}
+	if progress != nil {
+		progress(done)
+	}
+	return done, nil
+}
+
+// NewDumpHeader 读取环境和数据库信息，生成 mdb_dump 格式的文件头
+func NewDumpHeader(env *lmdb.Env, dbi lmdb.DBI, name string) (DumpHeader, error) {
+	header := DumpHeader{Database: name}
+	info, err := env.Info()
+	if err != nil {
+		return header, err
+	}
+	header.MapSize = info.MapSize
+	header.MaxReaders = info.MaxReaders
+	err = env.View(func(txn *lmdb.Txn) error {
+		stat, err := txn.Stat(dbi)
+		if err != nil {
+			return err
+		}
+		header.PageSize = stat.PSize
+		header.Flags, err = txn.Flags(dbi)
+		return err
+	})
+	return header, err
+}
@@ -0,0 +1,72 @@
+// Package transfer 实现键值对的导入导出格式：JSON Lines、CSV 以及 mdb_dump 文本格式
+package transfer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Format 为导入导出的文件格式
+type Format string
+
+const (
+	FormatJSONL   Format = "JSON Lines"
+	FormatCSV     Format = "CSV"
+	FormatMDBDump Format = "mdb_dump"
+)
+
+// Formats 返回所有支持的文件格式
+func Formats() []string {
+	return []string{string(FormatJSONL), string(FormatCSV), string(FormatMDBDump)}
+}
+
+// Encoding 为 JSON Lines 和 CSV 中键和值的编码方式，mdb_dump 固定使用十六进制（bytevalue）
+type Encoding string
+
+const (
+	EncodingBase64 Encoding = "base64"
+	EncodingHex    Encoding = "hex"
+	// EncodingText 直接使用文本，只用于导入，导出时无法保证二进制数据不被改写
+	EncodingText Encoding = "text"
+)
+
+// Encodings 返回导出支持的二进制安全的键值编码
+func Encodings() []string {
+	return []string{string(EncodingBase64), string(EncodingHex)}
+}
+
+// ImportEncodings 返回导入支持的键值编码
+func ImportEncodings() []string {
+	return append(Encodings(), string(EncodingText))
+}
+
+func (e Encoding) encode(data []byte) string {
+	if e == EncodingHex {
+		return hex.EncodeToString(data)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (e Encoding) decode(text string) ([]byte, error) {
+	switch e {
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(text)
+	case EncodingHex:
+		return hex.DecodeString(text)
+	case EncodingText:
+		return []byte(text), nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", string(e))
+}
+
+// FileExtension 返回格式对应的文件扩展名
+func (f Format) FileExtension() string {
+	switch f {
+	case FormatJSONL:
+		return ".jsonl"
+	case FormatCSV:
+		return ".csv"
+	}
+	return ".dump"
+}
@@ -0,0 +1,133 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// ConflictPolicy 决定导入的键已存在时如何处理
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+// ConflictPolicies 返回所有冲突处理策略
+func ConflictPolicies() []string {
+	return []string{string(ConflictSkip), string(ConflictOverwrite), string(ConflictFail)}
+}
+
+// maxImportErrors 为导入结果中保留的错误信息条数
+const maxImportErrors = 100
+
+// ImportResult 为导入的统计结果
+type ImportResult struct {
+	Inserted int
+	Skipped  int
+	Failed   int
+	Errors   []string // 前 maxImportErrors 条失败记录的原因
+}
+
+func (r *ImportResult) fail(err error) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+// ErrConflict 表示冲突策略为 fail 时遇到了已存在的键
+var ErrConflict = errors.New("key already exists")
+
+// Import 从 r 读取记录写入 dbi，每 batchSize 条记录提交一次写事务。
+// 键已存在时按 policy 处理（使用 MDB_NOOVERWRITE，DupSort 数据库使用 MDB_NODUPDATA，只有相同的键值对才算冲突）；
+// policy 为 fail 时遇到冲突立即停止，当前批次回滚，之前的批次保持已提交。
+// 格式错误或过长的记录计为失败并跳过。每提交一个批次调用一次 progress，参数为累计的结果和本批次写入的记录，
+// 记录中填写了在同一个事务中读取的写入前后键的所有值，可以加入历史。ctx 被取消时在批次之间停止
+func Import(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, r Reader, policy ConflictPolicy, batchSize int,
+	progress func(result ImportResult, changes []store.Change)) (ImportResult, error) {
+	var result ImportResult
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	var putFlags uint
+	if policy != ConflictOverwrite {
+		putFlags = lmdb.NoOverwrite
+		err := env.View(func(txn *lmdb.Txn) error {
+			flags, err := txn.Flags(dbi)
+			if flags&lmdb.DupSort != 0 {
+				putFlags = lmdb.NoDupData
+			}
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for eof := false; !eof; {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		batch := result
+		var changes []store.Change
+		err := env.Update(func(txn *lmdb.Txn) error {
+			for i := 0; i < batchSize; i++ {
+				key, val, err := r.Read()
+				if err == io.EOF {
+					eof = true
+					return nil
+				}
+				var recordErr *RecordError
+				if errors.As(err, &recordErr) {
+					batch.fail(err)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				before, err := store.ReadValues(txn, dbi, key)
+				if err != nil {
+					return err
+				}
+				err = txn.Put(dbi, key, val, putFlags)
+				switch {
+				case err == nil:
+					batch.Inserted++
+					after, err := store.ReadValues(txn, dbi, key)
+					if err != nil {
+						return err
+					}
+					changes = append(changes, store.Change{Kind: store.ChangePut, Key: key, Value: val, Before: before, After: after})
+				case lmdb.IsErrno(err, lmdb.KeyExist) && policy == ConflictSkip:
+					batch.Skipped++
+				case lmdb.IsErrno(err, lmdb.KeyExist):
+					batch.fail(fmt.Errorf("key %x: %w", key, ErrConflict))
+					return ErrConflict
+				case lmdb.IsErrno(err, lmdb.BadValSize):
+					batch.fail(fmt.Errorf("key %x: %w", key, err))
+				default:
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// 当前批次已回滚，只保留失败信息
+			result.Failed, result.Errors = batch.Failed, batch.Errors
+			return result, err
+		}
+		result = batch
+		if progress != nil {
+			progress(result, changes)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+func TestImportChanges(t *testing.T) {
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err := env.Open(t.TempDir(), 0, 0664); err != nil {
+		t.Fatal(err)
+	}
+	var dbi lmdb.DBI
+	err = env.Update(func(txn *lmdb.Txn) (err error) {
+		if dbi, err = txn.OpenRoot(0); err != nil {
+			return err
+		}
+		return txn.Put(dbi, []byte("a"), []byte("old"), 0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"key": "a", "value": "new"}` + "\n" + `{"key": "b", "value": "x"}` + "\n"
+	r, err := NewReader(strings.NewReader(input), FormatJSONL, EncodingText, CSVMapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batches [][]store.Change
+	result, err := Import(context.Background(), env, dbi, r, ConflictOverwrite, 1, func(_ ImportResult, changes []store.Change) {
+		batches = append(batches, changes)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != 2 || len(batches) < 2 || len(batches[0]) != 1 || len(batches[1]) != 1 {
+		t.Fatalf("result %+v, batches %+v", result, batches)
+	}
+	a := batches[0][0]
+	if string(a.Key) != "a" || joinValues(a.Before) != "old" || joinValues(a.After) != "new" {
+		t.Errorf("a: before %q after %q", a.Before, a.After)
+	}
+	b := batches[1][0]
+	if string(b.Key) != "b" || len(b.Before) != 0 || joinValues(b.After) != "x" {
+		t.Errorf("b: before %q after %q", b.Before, b.After)
+	}
+}
+
+func joinValues(values [][]byte) string {
+	return string(bytes.Join(values, []byte(",")))
+}
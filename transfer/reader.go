@@ -0,0 +1,274 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader 按某种格式逐条读取键值对，读完时返回 io.EOF。
+// 单条记录格式错误时返回 *RecordError，调用方可以跳过该记录继续读取
+type Reader interface {
+	Read() (key, val []byte, err error)
+}
+
+// RecordError 表示某一条记录无法解析
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// CSVMapping 指定 CSV 中键和值所在的列，列可以是表头中的列名，也可以是从 1 开始的列号
+type CSVMapping struct {
+	HasHeader   bool
+	KeyColumn   string
+	ValueColumn string
+}
+
+// NewReader 创建从 r 读取的 Reader。encoding 只对 JSON Lines 和 CSV 有效，mapping 只对 CSV 有效
+func NewReader(r io.Reader, format Format, encoding Encoding, mapping CSVMapping) (Reader, error) {
+	switch format {
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+		return &jsonlReader{scanner: scanner, encoding: encoding}, nil
+	case FormatCSV:
+		return newCSVReader(r, encoding, mapping)
+	case FormatMDBDump:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+		return &dumpReader{scanner: scanner}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", string(format))
+}
+
+// maxRecordSize 为一行记录的最大长度
+const maxRecordSize = 64 << 20
+
+type jsonlReader struct {
+	scanner  *bufio.Scanner
+	encoding Encoding
+	line     int
+}
+
+func (r *jsonlReader) Read() ([]byte, []byte, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, nil, &RecordError{Line: r.line, Err: err}
+		}
+		key, err := r.field(record, "key")
+		if err != nil {
+			return nil, nil, &RecordError{Line: r.line, Err: err}
+		}
+		val, err := r.field(record, "value")
+		if err != nil {
+			return nil, nil, &RecordError{Line: r.line, Err: err}
+		}
+		return key, val, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, io.EOF
+}
+
+// field 解码记录中的字段。text 编码下非字符串的 JSON 值按原样作为字节保存
+func (r *jsonlReader) field(record map[string]json.RawMessage, name string) ([]byte, error) {
+	raw, ok := record[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %q", name)
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		if r.encoding == EncodingText {
+			return []byte(raw), nil
+		}
+		return nil, fmt.Errorf("%q must be a string", name)
+	}
+	data, err := r.encoding.decode(text)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", name, err)
+	}
+	return data, nil
+}
+
+type csvReader struct {
+	csv         *csv.Reader
+	encoding    Encoding
+	keyColumn   int
+	valueColumn int
+}
+
+func newCSVReader(r io.Reader, encoding Encoding, mapping CSVMapping) (*csvReader, error) {
+	reader := &csvReader{csv: csv.NewReader(r), encoding: encoding}
+	reader.csv.FieldsPerRecord = -1
+	var header []string
+	if mapping.HasHeader {
+		var err error
+		header, err = reader.csv.Read()
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV header: %w", err)
+		}
+	}
+	var err error
+	if reader.keyColumn, err = csvColumn(header, mapping.KeyColumn); err != nil {
+		return nil, err
+	}
+	if reader.valueColumn, err = csvColumn(header, mapping.ValueColumn); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// csvColumn 将列名或从 1 开始的列号转换为列下标
+func csvColumn(header []string, column string) (int, error) {
+	column = strings.TrimSpace(column)
+	for i, name := range header {
+		if strings.TrimSpace(name) == column {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(column)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("unknown CSV column %q", column)
+	}
+	return n - 1, nil
+}
+
+func (r *csvReader) Read() ([]byte, []byte, error) {
+	record, err := r.csv.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, nil, &RecordError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return nil, nil, err
+	}
+	line, _ := r.csv.FieldPos(0)
+	if r.keyColumn >= len(record) || r.valueColumn >= len(record) {
+		return nil, nil, &RecordError{Line: line, Err: fmt.Errorf("expected columns %d and %d, got %d columns", r.keyColumn+1, r.valueColumn+1, len(record))}
+	}
+	key, err := r.encoding.decode(record[r.keyColumn])
+	if err != nil {
+		return nil, nil, &RecordError{Line: line, Err: fmt.Errorf("key: %w", err)}
+	}
+	val, err := r.encoding.decode(record[r.valueColumn])
+	if err != nil {
+		return nil, nil, &RecordError{Line: line, Err: fmt.Errorf("value: %w", err)}
+	}
+	return key, val, nil
+}
+
+// dumpReader 读取 mdb_dump 格式，支持 bytevalue 和 print 两种数据格式。
+// 文件中包含多个数据库时，所有数据库的记录都会被依次读取
+type dumpReader struct {
+	scanner   *bufio.Scanner
+	line      int
+	inData    bool
+	printable bool
+}
+
+func (r *dumpReader) Read() ([]byte, []byte, error) {
+	var key []byte
+	haveKey := false
+	for {
+		line, ok, err := r.nextLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok || r.inData && line == "DATA=END" {
+			r.inData = false
+			if haveKey {
+				return nil, nil, &RecordError{Line: r.line, Err: errors.New("key without value")}
+			}
+			if !ok {
+				return nil, nil, io.EOF
+			}
+			continue
+		}
+		if !r.inData {
+			r.readHeaderLine(line)
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			return nil, nil, &RecordError{Line: r.line, Err: fmt.Errorf("unexpected line %q", line)}
+		}
+		data, err := r.decode(line[1:])
+		if err != nil {
+			return nil, nil, &RecordError{Line: r.line, Err: err}
+		}
+		if !haveKey {
+			key, haveKey = data, true
+			continue
+		}
+		return key, data, nil
+	}
+}
+
+func (r *dumpReader) nextLine() (string, bool, error) {
+	if !r.scanner.Scan() {
+		return "", false, r.scanner.Err()
+	}
+	r.line++
+	return strings.TrimRight(r.scanner.Text(), "\r"), true, nil
+}
+
+func (r *dumpReader) readHeaderLine(line string) {
+	name, value, _ := strings.Cut(line, "=")
+	switch name {
+	case "format":
+		r.printable = value == "print"
+	case "HEADER":
+		r.inData = value == "END"
+	}
+}
+
+func (r *dumpReader) decode(text string) ([]byte, error) {
+	if !r.printable {
+		return hex.DecodeString(text)
+	}
+	// print 格式中 "\\" 表示反斜杠，"\xx" 表示一个十六进制字节
+	data := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			data = append(data, text[i])
+			continue
+		}
+		if i+1 < len(text) && text[i+1] == '\\' {
+			data = append(data, '\\')
+			i++
+			continue
+		}
+		if i+2 >= len(text) {
+			return nil, errors.New("truncated escape")
+		}
+		b, err := hex.DecodeString(text[i+1 : i+3])
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+		i += 2
+	}
+	return data, nil
+}
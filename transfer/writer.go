@@ -0,0 +1,125 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// Writer 按某种格式逐条写出键值对，写完后必须调用 Close 刷新缓冲
+type Writer interface {
+	Write(key, val []byte) error
+	Close() error
+}
+
+// DumpHeader 为 mdb_dump 格式文件头中的环境和数据库信息
+type DumpHeader struct {
+	Database   string // 命名数据库名称，根数据库为空
+	MapSize    int64
+	MaxReaders uint
+	PageSize   uint
+	Flags      uint // 数据库标志，例如 lmdb.DupSort
+}
+
+// JSONRecord 为 JSON Lines 格式中的一行，键和值按 Encoding 编码
+type JSONRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NewWriter 创建写入 w 的 Writer。encoding 只对 JSON Lines 和 CSV 有效，header 只对 mdb_dump 有效
+func NewWriter(w io.Writer, format Format, encoding Encoding, header DumpHeader) (Writer, error) {
+	buf := bufio.NewWriter(w)
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{buf: buf, encoder: json.NewEncoder(buf), encoding: encoding}, nil
+	case FormatCSV:
+		writer := &csvWriter{buf: buf, csv: csv.NewWriter(buf), encoding: encoding}
+		if err := writer.csv.Write([]string{"key", "value"}); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	case FormatMDBDump:
+		writer := &dumpWriter{buf: buf}
+		if err := writer.writeHeader(header); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", string(format))
+}
+
+type jsonlWriter struct {
+	buf      *bufio.Writer
+	encoder  *json.Encoder
+	encoding Encoding
+}
+
+func (w *jsonlWriter) Write(key, val []byte) error {
+	return w.encoder.Encode(JSONRecord{Key: w.encoding.encode(key), Value: w.encoding.encode(val)})
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.buf.Flush()
+}
+
+type csvWriter struct {
+	buf      *bufio.Writer
+	csv      *csv.Writer
+	encoding Encoding
+}
+
+func (w *csvWriter) Write(key, val []byte) error {
+	return w.csv.Write([]string{w.encoding.encode(key), w.encoding.encode(val)})
+}
+
+func (w *csvWriter) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// dumpWriter 输出 mdb_dump 的 bytevalue 格式，可直接用 mdb_load 导入
+type dumpWriter struct {
+	buf *bufio.Writer
+}
+
+func (w *dumpWriter) writeHeader(header DumpHeader) error {
+	fmt.Fprintln(w.buf, "VERSION=3")
+	fmt.Fprintln(w.buf, "format=bytevalue")
+	if header.Database != "" {
+		fmt.Fprintf(w.buf, "database=%s\n", header.Database)
+	}
+	fmt.Fprintln(w.buf, "type=btree")
+	fmt.Fprintf(w.buf, "mapsize=%d\n", header.MapSize)
+	fmt.Fprintf(w.buf, "maxreaders=%d\n", header.MaxReaders)
+	if header.Flags&lmdb.DupSort != 0 {
+		fmt.Fprintln(w.buf, "duplicates=1")
+	}
+	for _, name := range store.DBIFlagNames(header.Flags) {
+		fmt.Fprintf(w.buf, "%s=1\n", name)
+	}
+	fmt.Fprintf(w.buf, "db_pagesize=%d\n", header.PageSize)
+	_, err := fmt.Fprintln(w.buf, "HEADER=END")
+	return err
+}
+
+func (w *dumpWriter) Write(key, val []byte) error {
+	fmt.Fprintf(w.buf, " %s\n", hex.EncodeToString(key))
+	_, err := fmt.Fprintf(w.buf, " %s\n", hex.EncodeToString(val))
+	return err
+}
+
+func (w *dumpWriter) Close() error {
+	fmt.Fprintln(w.buf, "DATA=END")
+	return w.buf.Flush()
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/config"
+	"github.com/zshimonz/lmdb-gui-client/store"
+	"github.com/zshimonz/lmdb-gui-client/transform"
+)
+
+// 执行脚本时列表中显示的有变化的记录的最大数量，之后只统计不再显示
+const maxTransformResults = 1000
+
+// 新建脚本时的示例
+const transformTemplate = `# Called once for every record in the current key filter.
+# key and value are strings of raw bytes. Return:
+#   None                  keep the record unchanged
+#   a new value           str or bytes
+#   DELETE                delete the record
+#   (new_key, new_value)  move the record, new_value None keeps the value
+def transform(key, value):
+    if not key.startswith("user:"):
+        return None
+    doc = json.decode(value)
+    doc["active"] = True
+    return json.encode(doc)
+`
+
+var transformTabItem *fyne.Container
+var transformList *widget.List
+var transformTargetLabel *widget.Label
+var transformSummaryLabel *widget.Label
+var transformBeforeEntry *widget.Entry
+var transformAfterEntry *widget.Entry
+
+// transformResults 为预览或执行脚本得到的结果
+var transformResults []transform.Result
+
+// transformGeneration 每次开始或停止执行脚本时递增，旧的执行发现编号变化后不再写入结果
+var transformGeneration atomic.Int64
+var transformCancel context.CancelFunc
+var transformDone chan struct{}
+
+func initTransformTabItem(w fyne.Window) *fyne.Container {
+	transformTargetLabel = widget.NewLabel("")
+	scriptEntry := widget.NewMultiLineEntry()
+	scriptEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	scriptEntry.SetText(transformTemplate)
+
+	sampleSizeEntry := widget.NewEntry()
+	sampleSizeEntry.SetText("20")
+	batchSizeEntry := widget.NewEntry()
+	batchSizeEntry.SetText("1000")
+	dryRunCheck := widget.NewCheck("Dry Run", nil)
+	dryRunCheck.SetChecked(true)
+
+	transformSummaryLabel = widget.NewLabel("Preview the script on a sample before running it")
+	transformList = widget.NewList(
+		func() int { return len(transformResults) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(transformResultSummary(transformResults[i]))
+		},
+	)
+	transformBeforeEntry = widget.NewMultiLineEntry()
+	transformBeforeEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	transformBeforeEntry.Disable()
+	transformAfterEntry = widget.NewMultiLineEntry()
+	transformAfterEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	transformAfterEntry.Disable()
+	transformList.OnSelected = func(id widget.ListItemID) {
+		if id < 0 || id >= len(transformResults) {
+			return
+		}
+		result := transformResults[id]
+		transformBeforeEntry.SetText(formatValue(result.Value))
+		transformAfterEntry.SetText(transformResultAfter(result))
+	}
+	transformList.OnUnselected = func(id widget.ListItemID) {
+		transformBeforeEntry.SetText("")
+		transformAfterEntry.SetText("")
+	}
+
+	previewButton := widget.NewButtonWithIcon("Preview", theme.VisibilityIcon(), func() {
+		if !isPositiveInteger(sampleSizeEntry.Text) {
+			showErrorLog("Sample size must be a positive integer")
+			return
+		}
+		sampleSize, _ := strconv.Atoi(sampleSizeEntry.Text)
+		previewTransform(scriptEntry.Text, sampleSize)
+	})
+	runButton := widget.NewButtonWithIcon("Run", theme.MediaPlayIcon(), func() {
+		if !isPositiveInteger(batchSizeEntry.Text) {
+			showErrorLog("Batch size must be a positive integer")
+			return
+		}
+		batchSize, _ := strconv.Atoi(batchSizeEntry.Text)
+		runTransform(w, scriptEntry.Text, batchSize, dryRunCheck.Checked)
+	})
+	stopButton := widget.NewButtonWithIcon("Stop", theme.MediaStopIcon(), func() {
+		if transformCancel != nil {
+			transformCancel()
+		}
+	})
+	backButton := widget.NewButtonWithIcon("Back", theme.NavigateBackIcon(), func() {
+		stopTransform()
+		showKeyValesTabItem()
+	})
+
+	options := widget.NewForm(
+		widget.NewFormItem("Target", transformTargetLabel),
+		widget.NewFormItem("Sample Size", sampleSizeEntry),
+		widget.NewFormItem("Batch Size", container.NewBorder(nil, nil, nil, dryRunCheck, batchSizeEntry)),
+	)
+
+	beforeLabel := widget.NewLabel("Before")
+	beforeLabel.TextStyle = fyne.TextStyle{Bold: true}
+	afterLabel := widget.NewLabel("After")
+	afterLabel.TextStyle = fyne.TextStyle{Bold: true}
+	values := container.NewGridWithColumns(2,
+		container.NewBorder(beforeLabel, nil, nil, nil, transformBeforeEntry),
+		container.NewBorder(afterLabel, nil, nil, nil, transformAfterEntry))
+	resultsSplit := container.NewHSplit(container.NewBorder(transformSummaryLabel, nil, nil, nil, transformList), values)
+	resultsSplit.Offset = 0.35
+	split := container.NewVSplit(scriptEntry, resultsSplit)
+	split.Offset = 0.4
+
+	border := container.NewBorder(options, container.NewGridWithColumns(4, previewButton, runButton, stopButton, backButton), nil, nil, split)
+	border.Hide()
+	return border
+}
+
+func showTransformTabItem() {
+	if selectedConnectionIndex == -1 {
+		showErrorLog("No connection selected")
+		return
+	}
+	err := tabTitle.Set("Transform")
+	if err != nil {
+		return
+	}
+	refreshTransformTarget()
+	transformTabItem.Show()
+	compareTabItem.Hide()
+	historyTabItem.Hide()
+	pendingTabItem.Hide()
+	statsTabItem.Hide()
+	readersTabItem.Hide()
+	newKeyValesTabItem.Hide()
+	editConnectionTabItem.Hide()
+	newConnectionTabItem.Hide()
+	keyValuesTabItem.Hide()
+}
+
+// refreshTransformTarget 显示脚本将要处理的数据库和键范围，即键列表当前的前缀和范围过滤条件
+func refreshTransformTarget() {
+	if selectedConnectionIndex == -1 {
+		transformTargetLabel.SetText("")
+		return
+	}
+	text := config.Config.Connections[selectedConnectionIndex].Name + " / " + dbiDisplayName(selectedDBIName)
+	prefix, _ := keyPrefix.Get()
+	start, _ := rangeStart.Get()
+	end, _ := rangeEnd.Get()
+	if prefix == "" && start == "" && end == "" {
+		text += ", all keys"
+	}
+	if prefix != "" {
+		text += ", prefix " + strconv.Quote(prefix)
+	}
+	if start != "" || end != "" {
+		text += fmt.Sprintf(", range [%q, %q)", start, end)
+	}
+	transformTargetLabel.SetText(text)
+}
+
+// transformKeyRange 返回键列表当前的前缀和范围，脚本总是按键的升序执行
+func transformKeyRange() (store.KeyRange, error) {
+	prefix, _ := keyPrefix.Get()
+	keyRange, err := currentKeyRange(prefix)
+	keyRange.Descending = false
+	return keyRange, err
+}
+
+func transformResultSummary(result transform.Result) string {
+	text := fmt.Sprintf("%-9s %s", result.Kind, keyCodec.Format(result.Key))
+	if result.Kind == transform.Rename {
+		text += " → " + keyCodec.Format(result.NewKey)
+	}
+	return text
+}
+
+// transformResultAfter 返回结果中记录修改后的内容，以及脚本 print 的输出
+func transformResultAfter(result transform.Result) string {
+	var text string
+	switch result.Kind {
+	case transform.Unchanged:
+		text = "(unchanged)"
+	case transform.Delete:
+		text = "(deleted)"
+	case transform.Rename:
+		text = "Key: " + keyCodec.Format(result.NewKey) + "\n\n" + formatValue(result.NewValue)
+	case transform.Failed:
+		text = result.Err.Error()
+	default:
+		text = formatValue(result.NewValue)
+	}
+	if result.Output != "" {
+		text += "\n\n── print ──\n" + result.Output
+	}
+	return text
+}
+
+// transformRunning 表示执行脚本的协程仍在运行
+func transformRunning() bool {
+	if transformDone == nil {
+		return false
+	}
+	select {
+	case <-transformDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// stopTransform 停止正在执行的脚本，并等待协程结束。已提交的批次不会回滚
+func stopTransform() {
+	transformGeneration.Add(1)
+	if transformCancel != nil {
+		transformCancel()
+		transformCancel = nil
+	}
+	if transformDone != nil {
+		<-transformDone
+		transformDone = nil
+	}
+}
+
+func setTransformResults(results []transform.Result) {
+	transformResults = results
+	transformList.UnselectAll()
+	transformList.Refresh()
+}
+
+// previewTransform 在后台对当前范围内的前 sampleSize 条记录执行脚本并显示结果，不写入数据库
+func previewTransform(src string, sampleSize int) {
+	if transformRunning() {
+		showErrorLog("Wait for the script to finish")
+		return
+	}
+	keyRange, err := transformKeyRange()
+	if err != nil {
+		showErrorLog(err.Error())
+		return
+	}
+	script, err := transform.Compile(src)
+	if err != nil {
+		showErrorLog("Error compiling script: " + err.Error())
+		return
+	}
+
+	stopTransform()
+	generation := transformGeneration.Add(1)
+	done := make(chan struct{})
+	transformDone = done
+	setTransformResults(nil)
+	transformSummaryLabel.SetText("Previewing...")
+
+	previewEnv, previewDBI := env, dbi
+	go func() {
+		results, err := transform.Preview(previewEnv, previewDBI, keyRange, script, sampleSize)
+		close(done)
+		queueUIEvent(func() {
+			if transformGeneration.Load() != generation {
+				return
+			}
+			if err != nil {
+				showErrorLog("Error previewing script: " + err.Error())
+			}
+			setTransformResults(results)
+
+			counts := make(map[transform.Kind]int)
+			for _, result := range results {
+				counts[result.Kind]++
+			}
+			transformSummaryLabel.SetText(fmt.Sprintf("Preview of %d records: %d updated, %d deleted, %d moved, %d unchanged, %d errors",
+				len(results), counts[transform.Update], counts[transform.Delete], counts[transform.Rename], counts[transform.Unchanged], counts[transform.Failed]))
+		})
+	}()
+}
+
+// runTransform 在后台对当前范围内的所有记录执行脚本，按批次提交，dryRun 为 true 时只列出将要进行的修改
+func runTransform(w fyne.Window, src string, batchSize int, dryRun bool) {
+	if transformRunning() {
+		showErrorLog("Wait for the script to finish")
+		return
+	}
+	if !dryRun && connectionReadOnly {
+		showErrorLog("Connection is read-only")
+		return
+	}
+	keyRange, err := transformKeyRange()
+	if err != nil {
+		showErrorLog(err.Error())
+		return
+	}
+	script, err := transform.Compile(src)
+	if err != nil {
+		showErrorLog("Error compiling script: " + err.Error())
+		return
+	}
+	if dryRun {
+		startTransform(script, keyRange, batchSize, true)
+		return
+	}
+	message := fmt.Sprintf("Run the script on %s?\nChanges are committed every %d keys. If the script fails, the current batch is rolled back, earlier batches stay committed.",
+		transformTargetLabel.Text, batchSize)
+	dialog.ShowConfirm("Run Transform", message, func(ok bool) {
+		if ok {
+			startTransform(script, keyRange, batchSize, false)
+		}
+	}, w)
+}
+
+// startTransform 在后台执行脚本。结果在协程中收集，与进度一起放入界面事件队列中显示；
+// 每个已提交的批次加入开始时所在连接的历史，停止或切换连接后已提交的批次仍会记录
+func startTransform(script *transform.Script, keyRange store.KeyRange, batchSize int, dryRun bool) {
+	stopTransform()
+	generation := transformGeneration.Add(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	transformCancel, transformDone = cancel, done
+	setTransformResults(nil)
+	action := "Running"
+	if dryRun {
+		action = "Dry run"
+	}
+	transformSummaryLabel.SetText(action + "...")
+
+	transformEnv, transformDBI := env, dbi
+	connectionIndex, dbiName := selectedConnectionIndex, selectedDBIName
+	go func() {
+		var results []transform.Result
+		lastRefresh := time.Now()
+		summary, err := transform.Apply(ctx, transformEnv, transformDBI, keyRange, script, batchSize, dryRun,
+			func(result transform.Result) {
+				if len(results) < maxTransformResults {
+					results = append(results, result)
+				}
+			},
+			func(summary transform.Summary, changes []store.Change) {
+				if !dryRun && len(changes) > 0 {
+					queueUIEvent(func() {
+						recordConnectionHistory(connectionIndex, dbiName, changes)
+					})
+				}
+				if time.Since(lastRefresh) < searchRefreshInterval {
+					return
+				}
+				lastRefresh = time.Now()
+				// 之后追加的结果不会改变已放入队列的切片
+				shown := results[:len(results):len(results)]
+				queueUIEvent(func() {
+					if transformGeneration.Load() != generation {
+						return
+					}
+					transformResults = shown
+					transformSummaryLabel.SetText(action + "... " + transformSummaryText(summary))
+					transformList.Refresh()
+				})
+			})
+		close(done)
+		queueUIEvent(func() {
+			if transformGeneration.Load() != generation {
+				return
+			}
+			cancel()
+
+			transformResults = results
+			transformList.Refresh()
+			text := transformSummaryText(summary)
+			if dryRun {
+				text = "Dry run, nothing was written: " + text
+			}
+			switch {
+			case errors.Is(err, context.Canceled):
+				text += " (stopped)"
+			case err != nil:
+				text += " (stopped, the last batch was rolled back)"
+				showErrorLog("Error running script: " + err.Error())
+			}
+			if len(results) >= maxTransformResults {
+				text += fmt.Sprintf(", showing the first %d changes", maxTransformResults)
+			}
+			transformSummaryLabel.SetText(text)
+			if !dryRun && summary.Scanned > summary.Unchanged {
+				showInfoLog(fmt.Sprintf("Transformed %d records", summary.Scanned-summary.Unchanged))
+				queueReloadKeyValues(transformEnv, transformDBI)
+			}
+		})
+	}()
+}
+
+func transformSummaryText(summary transform.Summary) string {
+	return fmt.Sprintf("%d records in %d batches: %d updated, %d deleted, %d moved, %d unchanged",
+		summary.Scanned, summary.Batches, summary.Updated, summary.Deleted, summary.Renamed, summary.Unchanged)
+}
@@ -0,0 +1,363 @@
+// Package transform 使用 Starlark 脚本批量修改键范围内的记录
+package transform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// FuncName 为脚本中必须定义的函数，对每条记录调用一次
+const FuncName = "transform"
+
+// maxStepsPerRecord 为每条记录执行脚本的计算步数上限，避免脚本陷入过长的循环
+const maxStepsPerRecord = 10000000
+
+var errStop = errors.New("stop transform")
+
+// Kind 为脚本对一条记录的处理结果
+type Kind int
+
+const (
+	Unchanged Kind = iota
+	Update
+	Delete
+	Rename
+	Failed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Unchanged:
+		return "Unchanged"
+	case Update:
+		return "Update"
+	case Delete:
+		return "Delete"
+	case Rename:
+		return "Rename"
+	case Failed:
+		return "Error"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Result 为脚本对一条记录（DupSort 数据库中为一个重复值）的处理结果。
+// Rename 时 NewKey 为新的键，Update 和 Rename 时 NewValue 为新的值；Output 为脚本 print 的内容
+type Result struct {
+	Kind     Kind
+	Key      []byte
+	Value    []byte
+	NewKey   []byte
+	NewValue []byte
+	Output   string
+	Err      error
+}
+
+// deleteMarker 为脚本中的 DELETE，返回它表示删除记录
+type deleteMarker struct{}
+
+func (deleteMarker) String() string        { return "DELETE" }
+func (deleteMarker) Type() string          { return "delete" }
+func (deleteMarker) Freeze()               {}
+func (deleteMarker) Truth() starlark.Bool  { return starlark.True }
+func (deleteMarker) Hash() (uint32, error) { return 0, nil }
+
+// Script 为编译后的脚本，不能在多个协程中同时使用
+type Script struct {
+	thread *starlark.Thread
+	fn     starlark.Callable
+	output strings.Builder
+}
+
+// Compile 编译脚本。脚本需要定义 transform(key, value)，key 和 value 为原始字节组成的字符串，返回值：
+//
+//	None                 记录不变
+//	新的值（str 或 bytes） 更新值
+//	DELETE               删除记录
+//	(new_key, new_value) 将记录移动到新的键，new_value 为 None 时保留原来的值
+//
+// 脚本中可以使用 json.decode、json.encode 和 json.indent
+func Compile(src string) (*Script, error) {
+	s := &Script{}
+	s.thread = &starlark.Thread{Name: FuncName, Print: func(thread *starlark.Thread, msg string) {
+		s.output.WriteString(msg)
+		s.output.WriteByte('\n')
+	}}
+	predeclared := starlark.StringDict{
+		"json":   json.Module,
+		"DELETE": deleteMarker{},
+	}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, s.thread, "transform.star", src, predeclared)
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	fn, ok := globals[FuncName].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("the script must define %s(key, value)", FuncName)
+	}
+	s.fn = fn
+	return s, nil
+}
+
+// scriptError 为脚本的运行错误加上 Starlark 的调用栈
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
+
+// Run 对一条记录执行脚本，脚本出错时返回 Kind 为 Failed 的结果
+func (s *Script) Run(key, value []byte) Result {
+	result := Result{Key: append([]byte(nil), key...), Value: append([]byte(nil), value...)}
+	s.output.Reset()
+	s.thread.SetMaxExecutionSteps(s.thread.ExecutionSteps() + maxStepsPerRecord)
+	ret, err := starlark.Call(s.thread, s.fn, starlark.Tuple{starlark.String(key), starlark.String(value)}, nil)
+	result.Output = s.output.String()
+	if err == nil {
+		err = result.setReturn(ret)
+	}
+	if err != nil {
+		s.thread.Uncancel()
+		result.Kind, result.Err = Failed, scriptError(err)
+	}
+	return result
+}
+
+func (r *Result) setReturn(ret starlark.Value) error {
+	switch ret := ret.(type) {
+	case starlark.NoneType:
+		r.Kind = Unchanged
+	case deleteMarker:
+		r.Kind = Delete
+	case starlark.Tuple:
+		if len(ret) != 2 {
+			return fmt.Errorf("%s returned a tuple of %d elements, want (new_key, new_value)", FuncName, len(ret))
+		}
+		newKey, ok := bytesValue(ret[0])
+		if !ok || len(newKey) == 0 {
+			return fmt.Errorf("%s returned an invalid new key %s", FuncName, ret[0])
+		}
+		r.NewValue = r.Value
+		if ret[1] != starlark.None {
+			if r.NewValue, ok = bytesValue(ret[1]); !ok {
+				return fmt.Errorf("%s returned a %s value, want str or bytes", FuncName, ret[1].Type())
+			}
+		}
+		r.Kind, r.NewKey = Rename, newKey
+		if bytes.Equal(newKey, r.Key) {
+			r.Kind, r.NewKey = Update, nil
+		}
+	default:
+		newValue, ok := bytesValue(ret)
+		if !ok {
+			return fmt.Errorf("%s returned a %s, want None, str, bytes, DELETE or (new_key, new_value)", FuncName, ret.Type())
+		}
+		r.Kind, r.NewValue = Update, newValue
+	}
+	if r.Kind == Update && bytes.Equal(r.NewValue, r.Value) {
+		r.Kind, r.NewValue = Unchanged, nil
+	}
+	return nil
+}
+
+func bytesValue(v starlark.Value) ([]byte, bool) {
+	switch v := v.(type) {
+	case starlark.String:
+		return []byte(v), true
+	case starlark.Bytes:
+		return []byte(v), true
+	}
+	return nil, false
+}
+
+// Changes 返回应用结果所需的写操作。非 DupSort 数据库中更新前检查值未被修改；
+// DupSort 数据库中由 Apply 在每个键的第一个写操作上检查键的所有重复值未被修改。
+// 移动时检查新的键不存在，避免覆盖已有的记录
+func (r Result) Changes(dupSort bool) []store.Change {
+	switch r.Kind {
+	case Update:
+		if dupSort {
+			return []store.Change{{Kind: store.ChangeReplaceDup, Key: r.Key, Dup: r.Value, Value: r.NewValue}}
+		}
+		return []store.Change{{Kind: store.ChangePut, Key: r.Key, Value: r.NewValue, Before: [][]byte{r.Value}, Verify: true}}
+	case Delete:
+		if dupSort {
+			return []store.Change{{Kind: store.ChangeDeleteDup, Key: r.Key, Dup: r.Value}}
+		}
+		return []store.Change{{Kind: store.ChangeDelete, Key: r.Key, Before: [][]byte{r.Value}, Verify: true}}
+	case Rename:
+		if dupSort {
+			return []store.Change{
+				{Kind: store.ChangeDeleteDup, Key: r.Key, Dup: r.Value},
+				{Kind: store.ChangePut, Key: r.NewKey, Value: r.NewValue, Verify: true},
+			}
+		}
+		return []store.Change{
+			{Kind: store.ChangeDelete, Key: r.Key, Before: [][]byte{r.Value}, Verify: true},
+			{Kind: store.ChangePut, Key: r.NewKey, Value: r.NewValue, Verify: true},
+		}
+	}
+	return nil
+}
+
+// Preview 对 keyRange 内的前 limit 条记录执行脚本，不写入数据库
+func Preview(env *lmdb.Env, dbi lmdb.DBI, keyRange store.KeyRange, script *Script, limit int) ([]Result, error) {
+	results := make([]Result, 0, limit)
+	err := env.View(func(txn *lmdb.Txn) error {
+		return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+			if len(results) >= limit {
+				return errStop
+			}
+			results = append(results, script.Run(key, val))
+			return nil
+		})
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+	return results, err
+}
+
+// Summary 为执行脚本的统计
+type Summary struct {
+	Scanned   int // 执行脚本的记录数
+	Unchanged int
+	Updated   int
+	Deleted   int
+	Renamed   int
+	Batches   int // 已提交的批次数
+}
+
+func (s *Summary) add(kind Kind) {
+	s.Scanned++
+	switch kind {
+	case Unchanged:
+		s.Unchanged++
+	case Update:
+		s.Updated++
+	case Delete:
+		s.Deleted++
+	case Rename:
+		s.Renamed++
+	}
+}
+
+// Apply 按键的升序对 keyRange 内的每条记录执行脚本，每 batchSize 个键在一个写事务中提交一次，
+// DupSort 数据库中一个键的所有重复值总在同一个批次中。脚本出错或写入失败时停止，当前批次回滚，之前的批次保持已提交；
+// dryRun 为 true 时只执行脚本和统计，不写入数据库。
+// onResult 在每条有变化的记录上调用，onBatch 在每个批次提交后调用，可以为 nil。
+// 移动到范围内之后位置的键不会再次执行脚本，同一个键的多个重复值可以移动到同一个新的键。ctx 被取消时当前批次不会提交
+func Apply(ctx context.Context, env *lmdb.Env, dbi lmdb.DBI, keyRange store.KeyRange, script *Script, batchSize int, dryRun bool,
+	onResult func(Result), onBatch func(Summary, []store.Change)) (Summary, error) {
+	var summary Summary
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	keyRange.Descending = false
+	dupSort, err := store.IsDupSort(env, dbi)
+	if err != nil {
+		return summary, err
+	}
+	// renamed 为移动后写入的新键，遍历到时跳过
+	renamed := make(map[string]bool)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		batch := summary
+		var changes []store.Change
+		var lastKey []byte
+		keys := 0
+		more := false
+		// DupSort 数据库中 keyValues 为当前键读取到的所有重复值，verifyIndex 为当前键的第一个写操作在 changes 中的位置
+		var keyValues [][]byte
+		verifyIndex := -1
+		verifyKey := func() {
+			if verifyIndex >= 0 {
+				changes[verifyIndex].Before, changes[verifyIndex].Verify = keyValues, true
+			}
+			keyValues, verifyIndex = nil, -1
+		}
+		err := env.View(func(txn *lmdb.Txn) error {
+			return store.ScanRange(txn, dbi, keyRange, func(key, val []byte) error {
+				if !bytes.Equal(key, lastKey) {
+					verifyKey()
+					if keys == batchSize {
+						more = true
+						return errStop
+					}
+					keys++
+					lastKey = append(lastKey[:0], key...)
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if renamed[string(key)] {
+					return nil
+				}
+				if dupSort {
+					keyValues = append(keyValues, append([]byte(nil), val...))
+				}
+				result := script.Run(key, val)
+				if result.Kind == Failed {
+					return fmt.Errorf("key %q: %w", key, result.Err)
+				}
+				batch.add(result.Kind)
+				if result.Kind == Unchanged {
+					return nil
+				}
+				if onResult != nil {
+					onResult(result)
+				}
+				resultChanges := result.Changes(dupSort)
+				if dupSort && result.Kind == Rename && renamed[string(result.NewKey)] {
+					// 新的键已由之前的移动写入，之后的重复值加入该键
+					resultChanges[1].Verify = false
+				}
+				if result.Kind == Rename && !dryRun {
+					renamed[string(result.NewKey)] = true
+				}
+				if dupSort && verifyIndex < 0 {
+					verifyIndex = len(changes)
+				}
+				changes = append(changes, resultChanges...)
+				return nil
+			})
+		})
+		verifyKey()
+		if err != nil && !errors.Is(err, errStop) {
+			return summary, err
+		}
+
+		if len(changes) > 0 && !dryRun {
+			applied, err := store.ApplyChanges(env, dbi, changes)
+			if err != nil {
+				return summary, err
+			}
+			changes = applied
+		}
+		batch.Batches++
+		summary = batch
+		if onBatch != nil {
+			onBatch(summary, changes)
+		}
+		if !more {
+			return summary, nil
+		}
+		// 下一批从本批最后一个键之后开始
+		keyRange.Start = append(lastKey, 0)
+	}
+}
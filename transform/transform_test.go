@@ -0,0 +1,126 @@
+package transform
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+
+	"github.com/zshimonz/lmdb-gui-client/store"
+)
+
+// openDupSort 创建 DupSort 数据库，写入 a: [1, 2]、b: [3]
+func openDupSort(t *testing.T) (*lmdb.Env, lmdb.DBI) {
+	t.Helper()
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { env.Close() })
+	if err := env.SetMaxDBs(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Open(t.TempDir(), 0, 0664); err != nil {
+		t.Fatal(err)
+	}
+	var dbi lmdb.DBI
+	err = env.Update(func(txn *lmdb.Txn) (err error) {
+		if dbi, err = txn.OpenDBI("dups", lmdb.DupSort|lmdb.Create); err != nil {
+			return err
+		}
+		for _, kv := range [][2]string{{"a", "1"}, {"a", "2"}, {"b", "3"}} {
+			if err := txn.Put(dbi, []byte(kv[0]), []byte(kv[1]), 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, dbi
+}
+
+func readValues(t *testing.T, env *lmdb.Env, dbi lmdb.DBI, key string) []string {
+	t.Helper()
+	var values []string
+	err := env.View(func(txn *lmdb.Txn) error {
+		raw, err := store.ReadValues(txn, dbi, []byte(key))
+		for _, v := range raw {
+			values = append(values, string(v))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestApplyDupSortRename(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		newKey  string
+		wantErr bool
+		want    map[string][]string
+	}{
+		{"new key", "c", false, map[string][]string{"a": nil, "b": {"3"}, "c": {"1", "2"}}},
+		{"existing key", "b", true, map[string][]string{"a": {"1", "2"}, "b": {"3"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			env, dbi := openDupSort(t)
+			script, err := Compile(`
+def transform(key, value):
+    if key == "a":
+        return ("` + tt.newKey + `", None)
+    return None
+`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Apply(context.Background(), env, dbi, store.KeyRange{}, script, 10, false, nil, nil)
+			var conflict *store.ConflictError
+			if tt.wantErr != errors.As(err, &conflict) {
+				t.Fatalf("Apply = %v, want conflict %v", err, tt.wantErr)
+			}
+			for key, want := range tt.want {
+				if got := readValues(t, env, dbi, key); strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestChangesVerifyDupSortKey(t *testing.T) {
+	env, dbi := openDupSort(t)
+	script, err := Compile(`
+def transform(key, value):
+    return value + "!"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied []store.Change
+	_, err = Apply(context.Background(), env, dbi, store.KeyRange{}, script, 10, false, nil, func(_ Summary, changes []store.Change) {
+		applied = append(applied, changes...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readValues(t, env, dbi, "a"); len(got) != 2 || got[0] != "1!" || got[1] != "2!" {
+		t.Errorf("a = %q", got)
+	}
+	// 每个键的第一个写操作检查了读取时的所有重复值
+	var verified []string
+	for _, change := range applied {
+		if change.Verify {
+			verified = append(verified, string(change.Key))
+		}
+	}
+	if len(verified) != 2 || verified[0] != "a" || verified[1] != "b" {
+		t.Errorf("verified keys = %q, want [a b]", verified)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/zshimonz/lmdb-gui-client/codec"
+)
+
+// 值面板的显示模式，除 Text 和 Hex 外，其余模式为 codec 中注册的解码器名称
+const (
+	valueModeText = "Text"
+	valueModeHex  = "Hex"
+)
+
+var valueMode = valueModeText
+var valueModeSelect *widget.Select
+
+// keyDecoder 是根据选中键的前缀匹配到的 protobuf 解码器，没有匹配时为 nil
+var keyDecoder codec.ValueDecoder
+
+// currentValue 是当前加载到值编辑框中的原始字节
+var currentValue []byte
+
+// loadedValueText 是最近一次渲染到值编辑框中的文本，用于判断用户是否修改过值
+var loadedValueText string
+var valueEdited bool
+
+func initValueModeSelect() *widget.Select {
+	valueModeSelect = widget.NewSelect(valueModes(), func(mode string) {
+		if mode == "" || mode == valueMode {
+			return
+		}
+		val, err := valueViewBytes()
+		if err != nil {
+			showErrorLog("Error switching value mode: " + err.Error())
+			setValueModeSelected(valueMode)
+			return
+		}
+		edited := valueModified()
+		previousMode := valueMode
+		currentValue = val
+		valueMode = mode
+		if err := renderValueView(); err != nil {
+			showErrorLog("Error decoding value as " + mode + ": " + err.Error())
+			valueMode = previousMode
+			setValueModeSelected(valueMode)
+			_ = renderValueView()
+		}
+		valueEdited = edited
+	})
+	valueModeSelect.Selected = valueMode
+	return valueModeSelect
+}
+
+// valueModes 返回值面板可选的显示模式
+func valueModes() []string {
+	modes := []string{valueModeText, valueModeHex}
+	if keyDecoder != nil {
+		modes = append(modes, keyDecoder.Name())
+	}
+	return append(modes, codec.DecoderNames()...)
+}
+
+// decoderByName 按名称查找解码器，优先使用与选中键匹配的解码器
+func decoderByName(name string) codec.ValueDecoder {
+	if keyDecoder != nil && keyDecoder.Name() == name {
+		return keyDecoder
+	}
+	return codec.DecoderByName(name)
+}
+
+// setValueModeSelected 更新下拉框的选中项，不触发 OnChanged
+func setValueModeSelected(mode string) {
+	valueModeSelect.Selected = mode
+	valueModeSelect.Refresh()
+}
+
+// setValueViewBytes 将值加载到值编辑框中并自动选择显示模式：
+// 能被解码器识别的值使用对应的解码器，其余非 UTF-8 的值使用十六进制模式
+func setValueViewBytes(val []byte) {
+	currentValue = val
+	valueEdited = false
+	keyDecoder = nil
+	if selectedKey != "" {
+		keyDecoder = protoDecoderForKey(selectedKey)
+	}
+	valueModeSelect.Options = valueModes()
+	if keyDecoder != nil && len(val) > 0 {
+		// 键前缀映射到了 protobuf 消息，与 schema 不匹配时退回到不依赖 schema 的解码
+		valueMode = keyDecoder.Name()
+		if !keyDecoder.Detect(val) {
+			valueMode = codec.ProtoRawDecoderName
+		}
+	} else if decoder := codec.DetectDecoder(val); decoder != nil {
+		valueMode = decoder.Name()
+	} else if codec.IsText(val) {
+		valueMode = valueModeText
+	} else {
+		valueMode = valueModeHex
+	}
+	if err := renderValueView(); err != nil {
+		valueMode = valueModeHex
+		_ = renderValueView()
+	}
+	setValueModeSelected(valueMode)
+}
+
+// clearValueView 清空值编辑框
+func clearValueView() {
+	setValueViewBytes(nil)
+}
+
+func renderValueView() error {
+	text := ""
+	switch valueMode {
+	case valueModeHex:
+		text = codec.HexDump(currentValue)
+	case valueModeText:
+		text = formatValue(currentValue)
+	default:
+		var err error
+		text, err = decoderByName(valueMode).Decode(currentValue)
+		if err != nil {
+			return err
+		}
+	}
+	loadedValueText = text
+	if valueMode == valueModeText {
+		valueView.TextStyle = fyne.TextStyle{}
+		valueView.Wrapping = fyne.TextWrapWord
+	} else {
+		valueView.TextStyle = fyne.TextStyle{Monospace: true}
+		valueView.Wrapping = fyne.TextWrapOff
+	}
+	valueView.SetText(loadedValueText)
+	return nil
+}
+
+// valueModified 判断用户是否修改过值编辑框的内容
+func valueModified() bool {
+	return valueEdited || valueView.Text != loadedValueText
+}
+
+// valueViewBytes 返回值编辑框中的值。未修改时返回原始字节，保证二进制值不会被改写；
+// 使用解码器显示的值会按原来的格式重新编码
+func valueViewBytes() ([]byte, error) {
+	if !valueModified() {
+		return currentValue, nil
+	}
+	switch valueMode {
+	case valueModeHex:
+		return codec.ParseHexDump(valueView.Text)
+	case valueModeText:
+		return []byte(valueView.Text), nil
+	default:
+		return decoderByName(valueMode).Encode(valueView.Text, currentValue)
+	}
+}
@@ -0,0 +1,111 @@
+// Package watch 检测 LMDB 环境中的新提交，用于自动刷新
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PowerDNS/lmdb-go/lmdb"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DataFile 为环境目录中的数据文件名
+const DataFile = "data.mdb"
+
+// MinGap 为两次通知之间的最小间隔，写入频繁时合并为一次通知
+const MinGap = time.Second
+
+// LastTxnID 返回环境中最后一次提交的事务编号，其他进程的提交也会反映在其中
+func LastTxnID(env *lmdb.Env) (int64, error) {
+	info, err := env.Info()
+	if err != nil {
+		return 0, err
+	}
+	return info.LastTxnID, nil
+}
+
+// Watch 每隔 interval 检查一次环境的 LastTxnID，数据文件变化时立即检查，编号变化时调用 onChange，
+// 直到 ctx 被取消。无法监听文件时只按间隔检查。onChange 在 Watch 所在的协程中调用
+func Watch(ctx context.Context, env *lmdb.Env, databasePath string, interval time.Duration, onChange func(txnID int64)) error {
+	lastTxnID, err := LastTxnID(env)
+	if err != nil {
+		return err
+	}
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	if watcher, err := newFileWatcher(databasePath); err == nil {
+		defer watcher.Close()
+		fileEvents, fileErrors = watcher.Events, watcher.Errors
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastNotify time.Time
+	// delayed 在距离上次通知不足 MinGap 时延迟检查
+	var delayed <-chan time.Time
+	check := func() error {
+		if wait := MinGap - time.Since(lastNotify); wait > 0 {
+			if delayed == nil {
+				delayed = time.After(wait)
+			}
+			return nil
+		}
+		txnID, err := LastTxnID(env)
+		if err != nil {
+			return err
+		}
+		if txnID != lastTxnID {
+			lastTxnID = txnID
+			lastNotify = time.Now()
+			onChange(txnID)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-delayed:
+			delayed = nil
+		case _, ok := <-fileErrors:
+			// 监听出错时仍按间隔检查，错误需要读出，否则监听会阻塞
+			if !ok {
+				fileErrors = nil
+			}
+			continue
+		case event, ok := <-fileEvents:
+			if !ok {
+				fileEvents = nil
+				continue
+			}
+			if filepath.Base(event.Name) != DataFile || !event.Has(fsnotify.Write) {
+				continue
+			}
+		}
+		if err := check(); err != nil {
+			return err
+		}
+	}
+}
+
+// newFileWatcher 监听数据文件所在的目录，目录中的文件被替换后仍能收到事件
+func newFileWatcher(databasePath string) (*fsnotify.Watcher, error) {
+	dir := databasePath
+	if info, err := os.Stat(databasePath); err == nil && !info.IsDir() {
+		dir = filepath.Dir(databasePath)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}